@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the networks of cloud and hosting providers, by AS number
+var cloudASNs = map[int]string{
+	16509: "aws", 14618: "aws", 8987: "aws",
+	15169: "gcp", 396982: "gcp", 19527: "gcp", 36040: "gcp",
+	8075: "azure", 8068: "azure",
+	31898: "oracle", 45102: "alibaba", 37963: "alibaba", 132203: "tencent",
+	14061: "digitalocean", 63949: "linode", 20473: "vultr", 24940: "hetzner", 16276: "ovh",
+	13335: "cloudflare", 54113: "fastly", 20940: "akamai", 16625: "akamai",
+}
+
+// hostASN is the network an address of a host is announced from, for -asn
+type hostASN struct {
+	IP     string `json:"ip"`
+	ASN    int    `json:"asn"`
+	Name   string `json:"name,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+	Cloud  string `json:"cloud,omitempty"` // the provider of well known cloud and CDN networks, E.g. aws or gcp
+}
+
+// asnLookup looks up the networks of addresses with Team Cymru's DNS service, keeping the names of networks
+type asnLookup struct {
+	names map[int]string
+}
+
+func newASNLookup() *asnLookup {
+	return &asnLookup{names: make(map[int]string)}
+}
+
+// txt returns the fields of the first TXT record of name, separated by |
+func (l *asnLookup) txt(name string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	records, err := net.DefaultResolver.LookupTXT(ctx, name)
+	if err != nil || len(records) == 0 {
+		return nil
+	}
+	fields := strings.Split(records[0], "|")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	return fields
+}
+
+// lookup returns the network of ip, false if it isn't announced or can't be looked up
+func (l *asnLookup) lookup(ip string) (hostASN, bool) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return hostASN{}, false
+	}
+	var name string
+	if v4 := addr.To4(); v4 != nil {
+		name = strconv.Itoa(int(v4[3])) + "." + strconv.Itoa(int(v4[2])) + "." + strconv.Itoa(int(v4[1])) + "." +
+			strconv.Itoa(int(v4[0])) + ".origin.asn.cymru.com"
+	} else {
+		const hex = "0123456789abcdef"
+		var nibbles []string
+		for i := len(addr) - 1; i >= 0; i-- {
+			nibbles = append(nibbles, string(hex[addr[i]&0xf]), string(hex[addr[i]>>4]))
+		}
+		name = strings.Join(nibbles, ".") + ".origin6.asn.cymru.com"
+	}
+
+	// E.g. "15169 | 8.8.8.0/24 | US | arin | 2014-03-14", with several numbers first when announced by several
+	fields := l.txt(name)
+	if len(fields) < 2 {
+		return hostASN{}, false
+	}
+	numbers := strings.Fields(fields[0])
+	if len(numbers) == 0 {
+		return hostASN{}, false
+	}
+	asn, err := strconv.Atoi(numbers[0])
+	if err != nil {
+		return hostASN{}, false
+	}
+	if _, ok := l.names[asn]; !ok {
+		// E.g. "15169 | US | arin | 2000-03-30 | GOOGLE - Google LLC, US"
+		if fields := l.txt("AS" + strconv.Itoa(asn) + ".asn.cymru.com"); len(fields) >= 5 {
+			l.names[asn] = fields[4]
+		} else {
+			l.names[asn] = ""
+		}
+	}
+	return hostASN{IP: ip, ASN: asn, Name: l.names[asn], Prefix: fields[1], Cloud: cloudASNs[asn]}, true
+}
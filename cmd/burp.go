@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/palaziv/hakrawler/crawler"
+)
+
+// layout of the times in Burp's XML
+const burpTimeLayout = "Mon Jan 02 15:04:05 MST 2006"
+
+type burpCDATA struct {
+	Value string `xml:",cdata"`
+}
+
+type burpHost struct {
+	IP   string `xml:"ip,attr"`
+	Name string `xml:",chardata"`
+}
+
+type burpData struct {
+	Base64 bool   `xml:"base64,attr"`
+	Data   string `xml:",cdata"`
+}
+
+// burpItem is an item of Burp's XML export, a request without a response as nothing is kept of responses
+type burpItem struct {
+	XMLName        xml.Name  `xml:"item"`
+	Time           string    `xml:"time"`
+	URL            burpCDATA `xml:"url"`
+	Host           burpHost  `xml:"host"`
+	Port           int       `xml:"port"`
+	Protocol       string    `xml:"protocol"`
+	Method         burpCDATA `xml:"method"`
+	Path           burpCDATA `xml:"path"`
+	Extension      string    `xml:"extension"`
+	Request        burpData  `xml:"request"`
+	Status         string    `xml:"status"`
+	ResponseLength string    `xml:"responselength"`
+	MimeType       string    `xml:"mimetype"`
+	Response       burpData  `xml:"response"`
+	Comment        string    `xml:"comment"`
+}
+
+// burpSiteMap collects the requests of the results in the format of Burp's "Save items", with the headers of the
+// seed they were found from, for -burp
+type burpSiteMap struct {
+	items []burpItem
+	seen  map[string]bool
+}
+
+func newBurpSiteMap() *burpSiteMap {
+	return &burpSiteMap{seen: make(map[string]bool)}
+}
+
+// add records the request of a result, once for each method and url
+func (b *burpSiteMap) add(res crawler.Result) {
+	if crawler.IsFindingSource(res.Source) {
+		return
+	}
+	method, u, body, ok := resultRequest(res)
+	if !ok || b.seen[method+" "+u.String()] {
+		return
+	}
+	b.seen[method+" "+u.String()] = true
+
+	port := 80
+	if u.Scheme == "https" {
+		port = 443
+	}
+	if u.Port() != "" {
+		port, _ = strconv.Atoi(u.Port())
+	}
+	ext := extension(u.String())
+	if ext == "" {
+		ext = "null"
+	}
+	raw := formatRawRequest(method, u, body, headersFor(res.Seed))
+	b.items = append(b.items, burpItem{
+		Time:      time.Now().Format(burpTimeLayout),
+		URL:       burpCDATA{u.String()},
+		Host:      burpHost{Name: u.Hostname()},
+		Port:      port,
+		Protocol:  u.Scheme,
+		Method:    burpCDATA{method},
+		Path:      burpCDATA{u.RequestURI()},
+		Extension: ext,
+		Request:   burpData{Base64: true, Data: base64.StdEncoding.EncodeToString([]byte(raw))},
+		Response:  burpData{Base64: true},
+	})
+}
+
+// write saves the items to filename
+func (b *burpSiteMap) write(filename string) error {
+	bytes, err := xml.MarshalIndent(struct {
+		XMLName    xml.Name   `xml:"items"`
+		ExportTime string     `xml:"exportTime,attr"`
+		Items      []burpItem `xml:"item"`
+	}{ExportTime: time.Now().Format(burpTimeLayout), Items: b.items}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append([]byte(xml.Header), append(bytes, '\n')...), 0644)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+)
+
+// ANSI escape codes used for colored output
+const (
+	colorReset   = "\033[0m"
+	colorRed     = "\033[31m"
+	colorGreen   = "\033[32m"
+	colorYellow  = "\033[33m"
+	colorBlue    = "\033[34m"
+	colorMagenta = "\033[35m"
+	colorCyan    = "\033[36m"
+)
+
+// useColor is set when stdout is a terminal, unless -no-color or NO_COLOR is set
+var useColor bool
+
+// isTerminal returns whether f is a terminal rather than a pipe or a file
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && (stat.Mode()&os.ModeCharDevice) != 0
+}
+
+// paint wraps s in the supplied color when colored output is enabled
+func paint(color string, s string) string {
+	if !useColor || s == "" {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// withoutColor runs f with colored output disabled, E.g. to format the lines written to the -o file
+func withoutColor(f func()) {
+	saved := useColor
+	useColor = false
+	f()
+	useColor = saved
+}
+
+// tag returns name in brackets, colored by what kind of result it labels
+func tag(name string) string {
+	color := colorBlue
+	switch name {
+	case "broken":
+		color = colorRed
+	case "open-redirect", "directory-listing", "grep", "trap", "cdn", "waf", "truncated", "unchecked":
+		color = colorYellow
+	case "redirect":
+		color = colorMagenta
+	case "subdomain", "third-party", "cors", "cookie":
+		color = colorCyan
+	}
+	return "[" + paint(color, name) + "]"
+}
+
+// scopeTag returns the label for the scope verdict of a result, "in" or "out"
+func scopeTag(scope string) string {
+	if scope == "in" {
+		return "[" + paint(colorGreen, "in-scope") + "]"
+	}
+	return "[" + paint(colorYellow, "out-of-scope") + "]"
+}
+
+// statusColor returns the color for an HTTP status code
+func statusColor(status int) string {
+	switch {
+	case status >= 200 && status < 300:
+		return colorGreen
+	case status >= 300 && status < 400:
+		return colorYellow
+	}
+	return colorRed
+}
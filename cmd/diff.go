@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+// loadPreviousURLs reads the urls printed by a previous run, for -diff. Both plain and JSON output are understood.
+func loadPreviousURLs(filename string) (map[string]bool, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	urls := make(map[string]bool)
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "{") {
+			var record struct {
+				Type string `json:"type"`
+				URL  string
+			}
+			// lines with a type are errors and summaries rather than results
+			if json.Unmarshal([]byte(line), &record) == nil && record.Type == "" && record.URL != "" {
+				urls[record.URL] = true
+			}
+			continue
+		}
+		// plain lines are the url, possibly after [source] and [where] tags and followed by where it redirects to
+		for _, field := range strings.Fields(line) {
+			if !strings.HasPrefix(field, "[") && field != "->" {
+				urls[field] = true
+			}
+		}
+	}
+	return urls, s.Err()
+}
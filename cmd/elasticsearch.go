@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/palaziv/hakrawler/crawler"
+)
+
+// number of documents buffered before a bulk request is sent
+const esBatchSize = 500
+
+// esDocument is the shape of a Result as it is stored in Elasticsearch
+type esDocument struct {
+	Source string `json:"source"`
+	URL    string `json:"url"`
+	Where  string `json:"where"`
+	Host   string `json:"host"`
+	Path   string `json:"path"`
+}
+
+// esSink bulk-indexes results into an Elasticsearch index
+type esSink struct {
+	bulkURL string
+	index   string
+	client  *http.Client
+	buf     bytes.Buffer
+	pending int
+}
+
+// newESSink creates a sink from an index URL such as http://localhost:9200/hakrawler
+func newESSink(rawURL string) (*esSink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || !u.IsAbs() {
+		return nil, errors.New("input must be a valid absolute URL")
+	}
+	index := strings.Trim(u.Path, "/")
+	if index == "" || strings.Contains(index, "/") {
+		return nil, errors.New("URL path must be the name of the index")
+	}
+	u.Path = "/_bulk"
+	return &esSink{
+		bulkURL: u.String(),
+		index:   index,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Add buffers a result, sending a bulk request once the batch is full
+func (s *esSink) Add(res crawler.Result) {
+	doc := esDocument{
+		Source: res.Source,
+		URL:    res.URL,
+		Where:  res.Where,
+	}
+	if u, err := url.Parse(res.URL); err == nil {
+		doc.Host = u.Hostname()
+		doc.Path = u.Path
+	}
+
+	action, _ := json.Marshal(map[string]interface{}{"index": map[string]string{"_index": s.index}})
+	body, _ := json.Marshal(doc)
+	s.buf.Write(action)
+	s.buf.WriteByte('\n')
+	s.buf.Write(body)
+	s.buf.WriteByte('\n')
+	s.pending++
+
+	if s.pending >= esBatchSize {
+		if err := s.Flush(); err != nil {
+			log.Println("Error indexing results:", err)
+		}
+	}
+}
+
+// Flush sends all buffered documents to Elasticsearch
+func (s *esSink) Flush() error {
+	if s.pending == 0 {
+		return nil
+	}
+	defer func() {
+		s.buf.Reset()
+		s.pending = 0
+	}()
+
+	resp, err := s.client.Post(s.bulkURL, "application/x-ndjson", bytes.NewReader(s.buf.Bytes()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var reply struct {
+		Errors bool `json:"errors"`
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("bulk request failed with status %s", resp.Status)
+	}
+	if err != nil {
+		return fmt.Errorf("reading bulk response: %w", err)
+	}
+	if json.Unmarshal(body, &reply) == nil && reply.Errors {
+		return errors.New("some documents were rejected by Elasticsearch")
+	}
+	return nil
+}
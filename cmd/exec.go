@@ -0,0 +1,79 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/palaziv/hakrawler/crawler"
+)
+
+// executor runs a command for every in scope url found, E.g. a scanner
+type executor struct {
+	command string
+	sem     chan struct{}
+	wg      sync.WaitGroup
+
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// newExecutor returns an executor running at most threads commands at a time
+func newExecutor(command string, threads int) *executor {
+	if threads < 1 {
+		threads = 1
+	}
+	return &executor{command: command, sem: make(chan struct{}, threads), seen: make(map[string]bool)}
+}
+
+// Add queues the command for the url of a result, once per url
+func (x *executor) Add(res crawler.Result) {
+	if res.Scope != "in" {
+		return
+	}
+	x.mu.Lock()
+	seen := x.seen[res.URL]
+	x.seen[res.URL] = true
+	x.mu.Unlock()
+	if seen {
+		return
+	}
+
+	x.wg.Add(1)
+	go func() {
+		defer x.wg.Done()
+		x.sem <- struct{}{}
+		defer func() { <-x.sem }()
+
+		cmd := exec.Command("sh", "-c", expandCommand(x.command, res))
+		// the output of the commands is kept apart from the urls on stdout
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			log.Println("Error running command for "+res.URL+":", err)
+		}
+	}()
+}
+
+// Wait blocks until every command queued is done
+func (x *executor) Wait() {
+	x.wg.Wait()
+}
+
+// expandCommand replaces the {url}, {source}, {where} and {seed} placeholders of command with the values of res,
+// quoted for the shell
+func expandCommand(command string, res crawler.Result) string {
+	return strings.NewReplacer(
+		"{url}", shellQuote(res.URL),
+		"{source}", shellQuote(res.Source),
+		"{where}", shellQuote(res.Where),
+		"{seed}", shellQuote(res.Seed),
+	).Replace(command)
+}
+
+// shellQuote quotes s as a single word for sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
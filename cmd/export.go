@@ -0,0 +1,162 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/palaziv/hakrawler/crawler"
+)
+
+// characters replaced in the names of exported request files
+var unsafeFilenameRegex = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// requestExporter writes the in scope urls with parameters and the forms found as raw HTTP requests, one file per
+// endpoint and set of parameters, E.g. for sqlmap -r
+type requestExporter struct {
+	dir  string
+	seen map[string]bool
+}
+
+// newRequestExporter returns an exporter writing to dir, creating it if needed. Requests get the headers of the seed
+// they were found from.
+func newRequestExporter(dir string) (*requestExporter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &requestExporter{dir: dir, seen: make(map[string]bool)}, nil
+}
+
+// Add writes the request of a result if it has parameters and wasn't written before
+func (x *requestExporter) Add(res crawler.Result) {
+	if res.Scope != "in" || crawler.IsFindingSource(res.Source) {
+		return
+	}
+	method, u, body, ok := resultRequest(res)
+	if !ok || (u.RawQuery == "" && body == "") {
+		return
+	}
+
+	// endpoints are written once for each set of parameter names, whatever their values
+	names := paramNames(u.RawQuery)
+	names = append(names, paramNames(body)...)
+	sort.Strings(names)
+	key := method + " " + u.Scheme + "://" + u.Host + u.Path + " " + strings.Join(names, "&")
+	if x.seen[key] {
+		return
+	}
+	x.seen[key] = true
+
+	sum := sha1.Sum([]byte(key))
+	name := strings.Trim(unsafeFilenameRegex.ReplaceAllString(u.Host+u.Path, "_"), "_")
+	if len(name) > 80 {
+		name = name[:80]
+	}
+	filename := filepath.Join(x.dir, name+"_"+hex.EncodeToString(sum[:4])+".txt")
+	if err := os.WriteFile(filename, []byte(formatRawRequest(method, u, body, headersFor(res.Seed))), 0644); err != nil {
+		log.Println("Error exporting request:", err)
+	}
+}
+
+// resultRequest returns the request a result is fetched or submitted with: its method, url and body. The fields of
+// forms sent with GET are part of the query.
+func resultRequest(res crawler.Result) (string, *url.URL, string, bool) {
+	u, err := url.Parse(res.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", nil, "", false
+	}
+	u.Fragment = ""
+	method := res.Method
+	if method == "" {
+		method = "GET"
+	}
+	body := res.Body
+	if method == "GET" && body != "" {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += body
+		body = ""
+	}
+	return method, u, body, true
+}
+
+// paramNames returns the names of the parameters of a query string or url encoded body
+func paramNames(query string) []string {
+	var names []string
+	for _, pair := range strings.Split(query, "&") {
+		if pair != "" {
+			names = append(names, strings.SplitN(pair, "=", 2)[0])
+		}
+	}
+	return names
+}
+
+// formatRawRequest returns an HTTP/1.1 request as sent on the wire, with headers in name order
+func formatRawRequest(method string, u *url.URL, body string, headers map[string]string) string {
+	host := u.Host
+	if value, ok := headers["Host"]; ok {
+		host = value
+	}
+	var b strings.Builder
+	b.WriteString(method + " " + u.RequestURI() + " HTTP/1.1\r\n")
+	b.WriteString("Host: " + host + "\r\n")
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if _, ok := headers["User-Agent"]; !ok {
+		b.WriteString("User-Agent: " + crawler.UserAgent + "\r\n")
+	}
+	for _, name := range names {
+		if name != "Host" {
+			b.WriteString(name + ": " + headers[name] + "\r\n")
+		}
+	}
+	if body != "" {
+		if _, ok := headers["Content-Type"]; !ok {
+			b.WriteString("Content-Type: application/x-www-form-urlencoded\r\n")
+		}
+		b.WriteString("Content-Length: " + strconv.Itoa(len(body)) + "\r\n")
+	}
+	b.WriteString("\r\n" + body)
+	return b.String()
+}
+
+// curlCommand returns a curl command line sending the request of a result with headers, for -format curl
+func curlCommand(res crawler.Result, headers map[string]string, insecure bool) (string, bool) {
+	method, u, body, ok := resultRequest(res)
+	if !ok {
+		return "", false
+	}
+	command := "curl"
+	if insecure {
+		command += " -k"
+	}
+	if method != "GET" {
+		command += " -X " + method
+	}
+	if _, ok := headers["User-Agent"]; !ok {
+		command += " -H " + shellQuote("User-Agent: "+crawler.UserAgent)
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		command += " -H " + shellQuote(name+": "+headers[name])
+	}
+	if body != "" {
+		command += " --data-raw " + shellQuote(body)
+	}
+	return command + " " + shellQuote(u.String()), true
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"math/bits"
+	"net/http"
+	"strings"
+)
+
+// largest favicon hashed
+const maxFaviconSize = 1 << 20
+
+// faviconHash fetches /favicon.ico from origin, E.g. https://example.com, and returns its hash the way Shodan computes
+// http.favicon.hash, so hosts serving the same favicon can be found there. The request is sent with headers.
+func faviconHash(client *http.Client, origin string, headers map[string]string) (int32, bool) {
+	req, err := http.NewRequest(http.MethodGet, origin+"/favicon.ico", nil)
+	if err != nil {
+		return 0, false
+	}
+	for header, value := range headers {
+		req.Header.Set(header, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, false
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFaviconSize))
+	if err != nil || len(data) == 0 {
+		return 0, false
+	}
+	return int32(murmur3([]byte(encodeBase64Lines(data)))), true
+}
+
+// encodeBase64Lines encodes data like Python's base64.encodebytes, which Shodan hashes: lines of 76 characters,
+// each ending with a newline
+func encodeBase64Lines(data []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\n")
+	return b.String()
+}
+
+// murmur3 returns the 32 bit MurmurHash3 of data, with a seed of 0
+func murmur3(data []byte) uint32 {
+	const c1, c2 = 0xcc9e2d51, 0x1b873593
+	var h uint32
+	n := len(data) / 4
+	for i := 0; i < n; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	tail := data[n*4:]
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
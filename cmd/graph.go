@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/palaziv/hakrawler/crawler"
+)
+
+type graphEdge struct {
+	from, to int
+}
+
+// linkGraph collects which pages link to which urls, for -graph
+type linkGraph struct {
+	ids     map[string]int // url -> node, numbered in the order the urls are seen
+	urls    []string
+	edges   []graphEdge
+	sources map[graphEdge]string // how the first link of each edge was found, E.g. href or script
+}
+
+func newLinkGraph() *linkGraph {
+	return &linkGraph{ids: make(map[string]int), sources: make(map[graphEdge]string)}
+}
+
+func (g *linkGraph) node(u string) int {
+	id, ok := g.ids[u]
+	if !ok {
+		id = len(g.urls)
+		g.ids[u] = id
+		g.urls = append(g.urls, u)
+	}
+	return id
+}
+
+// add records the link of a result from the page it was found on
+func (g *linkGraph) add(res crawler.Result) {
+	if crawler.IsFindingSource(res.Source) || res.Where == "" || res.Where == res.URL {
+		return
+	}
+	edge := graphEdge{g.node(res.Where), g.node(res.URL)}
+	if _, ok := g.sources[edge]; !ok {
+		g.sources[edge] = res.Source
+		g.edges = append(g.edges, edge)
+	}
+}
+
+// write saves the graph to filename, as GraphML if it ends in .graphml and as Graphviz DOT otherwise
+func (g *linkGraph) write(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if strings.HasSuffix(strings.ToLower(filename), ".graphml") {
+		g.writeGraphML(w)
+	} else {
+		g.writeDOT(w)
+	}
+	return w.Flush()
+}
+
+func (g *linkGraph) writeDOT(w *bufio.Writer) {
+	w.WriteString("digraph hakrawler {\n")
+	for _, edge := range g.edges {
+		w.WriteString("  " + strconv.Quote(g.urls[edge.from]) + " -> " + strconv.Quote(g.urls[edge.to]))
+		if source := g.sources[edge]; source != "href" {
+			w.WriteString(" [label=" + strconv.Quote(source) + "]")
+		}
+		w.WriteString(";\n")
+	}
+	w.WriteString("}\n")
+}
+
+func (g *linkGraph) writeGraphML(w *bufio.Writer) {
+	w.WriteString(xml.Header)
+	w.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	w.WriteString(`  <key id="url" for="node" attr.name="url" attr.type="string"/>` + "\n")
+	w.WriteString(`  <key id="source" for="edge" attr.name="source" attr.type="string"/>` + "\n")
+	w.WriteString(`  <graph id="hakrawler" edgedefault="directed">` + "\n")
+	for id, u := range g.urls {
+		w.WriteString(`    <node id="n` + strconv.Itoa(id) + `"><data key="url">`)
+		xml.EscapeText(w, []byte(u))
+		w.WriteString("</data></node>\n")
+	}
+	for _, edge := range g.edges {
+		w.WriteString(`    <edge source="n` + strconv.Itoa(edge.from) + `" target="n` + strconv.Itoa(edge.to) + `"><data key="source">`)
+		xml.EscapeText(w, []byte(g.sources[edge]))
+		w.WriteString("</data></edge>\n")
+	}
+	w.WriteString("  </graph>\n</graphml>\n")
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+
+	"github.com/palaziv/hakrawler/crawler"
+	hakrawlerpb "github.com/palaziv/hakrawler/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcServer serves the jobs of a server through the gRPC API defined in proto/hakrawler.proto
+type grpcServer struct {
+	hakrawlerpb.UnimplementedHakrawlerServer
+	srv *server
+}
+
+func newGRPCServer(srv *server) *grpc.Server {
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := srv.authorizeRPC(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(impl interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := srv.authorizeRPC(ss.Context()); err != nil {
+				return err
+			}
+			return handler(impl, ss)
+		}),
+	)
+	hakrawlerpb.RegisterHakrawlerServer(s, &grpcServer{srv: srv})
+	return s
+}
+
+// authorizeRPC checks the server's token in the authorization metadata of a call, like the Authorization header
+func (s *server) authorizeRPC(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	authorization := ""
+	if values := md.Get("authorization"); len(values) > 0 {
+		authorization = values[0]
+	}
+	if !s.validToken(authorization) {
+		return status.Error(codes.Unauthenticated, "missing or invalid token")
+	}
+	return nil
+}
+
+// SubmitCrawl starts a job like POST /jobs
+func (g *grpcServer) SubmitCrawl(ctx context.Context, in *hakrawlerpb.SubmitCrawlRequest) (*hakrawlerpb.SubmitCrawlResponse, error) {
+	req := defaultJobRequest()
+	req.URLs = in.GetUrls()
+	if c := in.GetConfig(); c != nil {
+		req.Headers = c.GetHeaders()
+		req.Inside = c.GetInside()
+		req.Subs = c.GetSubs()
+		req.Insecure = c.GetInsecure()
+		req.DisableRedirects = c.GetDisableRedirects()
+		req.Proxy = c.GetProxy()
+		if len(c.GetNoVisit()) > 0 {
+			req.NoVisit = c.GetNoVisit()
+		} else if c.GetVisitAll() {
+			req.NoVisit = []string{}
+		}
+		if c.Depth != nil {
+			req.Depth = int(c.GetDepth())
+		}
+		if c.Size != nil {
+			req.Size = int(c.GetSize())
+		}
+		if c.Threads != nil {
+			req.Threads = int(c.GetThreads())
+		}
+		if c.Timeout != nil {
+			req.Timeout = int(c.GetTimeout())
+		}
+	}
+	j, err := g.srv.submit(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &hakrawlerpb.SubmitCrawlResponse{Id: j.ID}, nil
+}
+
+// StreamResults sends the results of a job like GET /jobs/{id}/results
+func (g *grpcServer) StreamResults(in *hakrawlerpb.StreamResultsRequest, stream hakrawlerpb.Hakrawler_StreamResultsServer) error {
+	j, ok := g.srv.job(in.GetId())
+	if !ok {
+		return status.Error(codes.NotFound, "no job "+in.GetId())
+	}
+	var sendErr error
+	j.follow(stream.Context(), func(res crawler.Result) error {
+		sendErr = stream.Send(protoResult(res))
+		return sendErr
+	}, func() {})
+	if sendErr == nil && stream.Context().Err() != nil {
+		return status.FromContextError(stream.Context().Err()).Err()
+	}
+	return sendErr
+}
+
+func protoResult(res crawler.Result) *hakrawlerpb.Result {
+	return &hakrawlerpb.Result{
+		Source:    res.Source,
+		Url:       res.URL,
+		Where:     res.Where,
+		Seed:      res.Seed,
+		Depth:     int32(res.Depth),
+		Chain:     res.Chain,
+		Redirects: res.Redirects,
+		FinalUrl:  res.FinalURL,
+		Status:    int32(res.Status),
+		Error:     res.Error,
+		Kind:      res.Kind,
+		Scope:     res.Scope,
+		Match:     res.Match,
+		Method:    res.Method,
+		Body:      res.Body,
+	}
+}
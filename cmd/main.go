@@ -2,14 +2,27 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net"
+	"net/http"
 	"net/url"
 	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"text/template"
+	"time"
 
 	"github.com/palaziv/hakrawler/crawler"
 )
@@ -19,99 +32,955 @@ var headers map[string]string
 // Thread safe map
 var sm sync.Map
 
+// the headers each seed is crawled with, which those of JSON targets add to
+var seedHeaders sync.Map
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serve(os.Args[2:])
+		return
+	}
+
 	inside := flag.Bool("i", false, "Only crawl inside path")
 	threads := flag.Int("t", 8, "Number of threads to utilise.")
+	limits := flag.String("limit", "", "Comma separated limits for some hosts, as glob=parallelism or glob=parallelism/delay. Other hosts are limited by -t. E.g. -limit api.example.com=1/2s,static.example.com=32")
 	depth := flag.Int("d", 2, "Depth to crawl.")
-	maxSize := flag.Int("size", -1, "Page size limit, in KB.")
+	prioritize := flag.Bool("prioritize", false, "Crawl the urls likely to be interesting first, those with parameters, under API paths such as /api/ or with extensions such as .php and .json, and pagination and assets last. Useful when -max-total-requests or -timeout cut the crawl short.")
+	strategy := flag.String("strategy", "", "Order to crawl in: breadth for the pages closest to the seed first, or depth for the links of the page crawled last first. By default pages are crawled as their links are found.")
+	maxSize := flag.Int("size", -1, "Page size limit, in KB. Bodies are cut off once they reach it, whether or not they have a Content-Length, and the pages cut off are reported as [truncated].")
+	binary := flag.Bool("binary", false, "Download binary files such as archives, PDFs and images. By default their download stops once their Content-Type is known.")
+	headFirst := flag.Bool("head-first", false, "Send a HEAD request for every link found, and only download the HTML pages and scripts within the size limit.")
+	filterSize := flag.String("filter-size", "", "Comma separated page sizes in bytes, or ranges of them, to skip the pages of. E.g. -filter-size 1234,2000-2100")
+	matchSize := flag.String("match-size", "", "Comma separated page sizes in bytes, or ranges of them, to only parse the pages of. E.g. -match-size 1000-,0-500")
 	insecure := flag.Bool("insecure", false, "Disable TLS verification.")
 	subsInScope := flag.Bool("subs", false, "Include subdomains for crawling.")
-	showJson := flag.Bool("json", false, "Output as JSON.")
-	showSource := flag.Bool("s", false, "Show the source of URL based on where it was found. E.g. href, form, script, etc.")
+	paths := flag.String("paths", "", "Comma separated path prefixes to restrict crawling and output to. E.g. -paths /api,/admin")
+	excludeSubs := flag.String("exclude-subs", "", "Comma separated subdomains to keep out of scope with -subs. E.g. -exclude-subs dev,staging,cdn")
+	showJson := flag.Bool("json", false, "Output as JSON. Requests that fail are written to stderr as {\"type\":\"error\",...} lines.")
+	format := flag.String("format", "plain", "Output format: plain, json, template or curl, which shows a curl command for each result with the -h and -H headers, and the method and fields of forms.")
+	outputFile := flag.String("o", "", "File to write the results to in the -format format, while stdout shows them as plain urls. E.g. -o results.json -format json")
+	templateText := flag.String("template", "", "Go template rendered for each result with -format template. E.g. -template '{{.Source}} {{.URL}} {{.Status}}'")
+	showScope := flag.Bool("show-oos", false, "Label each url as [in-scope] or [out-of-scope]. JSON output always has the label, as Scope.")
+	showSource := flag.Bool("s", false, "Show the source of URL based on where it was found. E.g. href, form, script, csp, etc.")
 	showWhere := flag.Bool("w", false, "Show at which link the URL is found.")
 	rawHeaders := flag.String(("h"), "", "Custom headers separated by two semi-colons. E.g. -h \"Cookie: foo=bar;;Referer: http://example.com/\" ")
+	headersFile := flag.String("H", "", "File with custom headers, one \"Name: value\" per line. Headers from -h take precedence. E.g. -H headers.txt")
+	ntlm := flag.String("ntlm", "", "Windows account to answer the NTLM and Negotiate challenges of hosts in scope with, as domain\\user:password or user:password. Kerberos isn't supported, servers offering Negotiate fall back to NTLM. E.g. -ntlm 'CORP\\alice:Passw0rd'")
+	oauth2File := flag.String("oauth2", "", "JSON file with an OAuth2 client credentials grant, whose access token is sent as a bearer token and renewed when it expires. E.g. {\"token_url\": \"https://auth.example.com/oauth/token\", \"client_id\": \"..\", \"client_secret\": \"..\", \"scopes\": [\"read\"], \"hosts\": [\"api.example.com\"]}")
+	awsSigV4 := flag.String("aws-sigv4", "", "Sign every request with AWS Signature Version 4 for a region and service, with the credentials in the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables. E.g. -aws-sigv4 us-east-1/execute-api")
+	headerConfig := flag.String("header-config", "", "File with headers to send only to some hosts, one \"host Name: value\" per line. E.g. \"api.example.com Authorization: Bearer xyz\" or \"*.example.com Cookie: session=abc\"")
+	requestFile := flag.String("request", "", "File with a raw HTTP request, E.g. saved from Burp, to crawl instead of the urls from stdin. Its method, body and headers are used for the first request, and its headers for all of them. E.g. -request req.txt")
+	diffFile := flag.String("diff", "", "Show only the urls that aren't in the output of a previous run, plain or JSON. E.g. -diff old_results.txt")
 	unique := flag.Bool(("u"), false, "Show only unique urls.")
+	matchExt := flag.String("match-ext", "", "Comma separated extensions; show only urls with one of them. E.g. -match-ext js,php,aspx")
+	grep := flag.String("grep", "", "Also report the pages crawled that match a regex, with the match and some context, as [grep]. E.g. -grep '(?i)internal|staging|stack trace'")
+	dirListings := flag.Bool("dir-listings", false, "Also report pages that are directory listings as [directory-listing].")
+	checkLinks := flag.Bool("check-links", false, "Also report links that return 404, 410 or a server error, or that can't be reached, as [broken]. Links matching -no-visit or disallowed by robots.txt with -respect-robots aren't requested and are reported as [unchecked].")
+	openRedirects := flag.Bool("open-redirects", false, "Also report urls with query parameters that look like redirect targets, E.g. ?next=https://.. as [open-redirect].")
+	finalURL := flag.Bool("final-url", false, "Also show the url each link resolves to after redirects. Links are printed once they have been visited.")
+	showRedirects := flag.Bool("show-redirects", false, "Show the redirect chains that were followed. They are always included in JSON output.")
+	cors := flag.Bool("cors", false, "At the end of the run, show the CORS headers returned by each endpoint crawled.")
+	cookies := flag.Bool("cookies", false, "At the end of the run, show the cookies set by each host crawled, with their Secure, HttpOnly and SameSite attributes.")
+	corsOrigin := flag.String("cors-origin", "", "Origin header to send with every request to probe CORS, implies -cors. E.g. -cors-origin https://evil.com")
+	thirdParty := flag.Bool("third-party", false, "At the end of the run, show the out of scope domains referenced by each url from stdin.")
+	showSubdomains := flag.Bool("show-subdomains", false, "Show only the unique subdomains of the target seen in links, scripts and TLS certificates.")
+	routes := flag.Bool("routes", false, "Also show the client-side routes of single page apps: hash routes such as #/admin and paths passed to history.pushState or defined in routers in inline scripts.")
+	jsOnly := flag.Bool("js-only", false, "Show only JavaScript file urls, deduplicated per host and path, including those referenced in inline scripts.")
+	queryURLs := flag.Bool("qurls", false, "Show only urls that have a query string.")
+	targetIP := flag.String("target-ip", "", "IP address to connect to for the hosts in scope instead of resolving them. With a Host header, the seed is requested and scoped by that virtual host. E.g. -target-ip 10.0.0.5 -h \"Host: staging.example.com\"")
 	proxy := flag.String(("proxy"), "", "Proxy URL. E.g. -proxy http://127.0.0.1:8080")
+	proxyFile := flag.String("proxy-file", "", "File with proxy URLs, one per line. When a host serves a WAF block page, or 5 403 or 429 responses in a row, the rest of its requests go through the next proxy, and the blocked page or 429 is retried through it. E.g. -proxy-file proxies.txt")
 	timeout := flag.Int("timeout", -1, "Maximum time to crawl each URL from stdin, in seconds.")
-	disableRedirects := flag.Bool("dr", false, "Disable following HTTP redirects.")
+	requestTimeout := flag.Int("request-timeout", 0, "Maximum time for each request, in seconds. 0 for no limit.")
+	disableRedirects := flag.Bool("dr", false, "Disable following HTTP redirects. Where they point to is shown instead.")
+	ignoreCase := flag.Bool("ignore-case", false, "Take urls whose paths only differ by case, such as /Admin and /admin, as the same url when deduplicating with -u and visiting. Useful for IIS, where paths are case insensitive.")
+	ignoreSlash := flag.Bool("ignore-trailing-slash", false, "Take /path and /path/ as the same url when deduplicating with -u and visiting.")
+	stripTracking := flag.Bool("strip-tracking", false, "Remove tracking parameters such as utm_source, gclid and fbclid from the urls found, so urls differing only by them are printed and crawled once.")
+	sessionParams := flag.String("session-params", strings.Join(crawler.DefaultSessionParams, ","), "Comma separated parameters holding session IDs, removed from the query and path parameters (;jsessionid=) of links so each page is crawled and printed once. Set to \"\" to keep them.")
+	noVisit := flag.String("no-visit", strings.Join(crawler.DefaultNoVisit, ","), "Comma separated words; URLs containing any of them in the path or query are printed but never visited. Set to \"\" to visit everything.")
+	polite := flag.Bool("polite", false, "Honor Crawl-delay from robots.txt and Retry-After from 429/503 responses.")
+	respectRobots := flag.Bool("respect-robots", false, "Obey the target's robots.txt rules.")
+	respectNofollow := flag.Bool("respect-nofollow", false, "Print but don't follow the links marked rel=nofollow, and the links of pages with a nofollow robots meta tag or X-Robots-Tag header.")
+	dedupeScheme := flag.Bool("dedupe-scheme", false, "Crawl only the first of the http:// and https:// variants of a url from stdin. Duplicate urls are always skipped.")
+	monitor := flag.Bool("monitor", false, "Crawl the urls from stdin again every -interval, showing only the urls that weren't seen before. Runs until interrupted or -max-runtime is reached.")
+	breaker := flag.Int("breaker", 5, "Number of requests in a row to a host that get no response, E.g. because of timeouts or refused connections, after which the host is skipped for -breaker-cooldown. 0 to never skip hosts.")
+	breakerCooldown := flag.Duration("breaker-cooldown", time.Minute, "Time hosts are skipped for once -breaker is reached.")
+	idleConns := flag.Int("idle-conns", 0, "Idle connections kept open to each host for reuse. 0 for the number of threads (-t).")
+	noKeepAlive := flag.Bool("no-keepalive", false, "Close connections after every request instead of reusing them.")
+	tlsTimeout := flag.Duration("tls-timeout", 10*time.Second, "Time allowed for TLS handshakes. 0 for no limit.")
+	maxBandwidth := flag.String("max-bandwidth", "", "Maximum rate response bodies are downloaded at across the run, E.g. 5MB/s or 500KB/s.")
+	pprofAddr := flag.String("pprof", "", "Address to serve the net/http/pprof profiling endpoints on during the run, E.g. localhost:6060.")
+	dnsTTL := flag.Duration("dns-ttl", 5*time.Minute, "Time the addresses of hosts are cached for across the run. 0 to resolve hosts for every connection.")
+	interval := flag.Duration("interval", 6*time.Hour, "Time between the crawls of -monitor, E.g. 30m.")
+	maxRuntime := flag.Duration("max-runtime", 0, "Maximum time for the whole run across all urls from stdin, e.g. 30m. Results found so far are still printed.")
+	detectWAF := flag.Bool("waf", false, "Recognise the WAFs hosts are behind, E.g. Cloudflare or Akamai, by their headers, cookies and block pages, reporting them as [waf] and in -report. Requests to hosts whose WAF blocks them are slowed down.")
+	rotateUserAgent := flag.Bool("rotate-user-agent", false, "With -waf, retry requests blocked by a WAF once, and send the next requests to that host with another browser's User-Agent.")
+	skipCDN := flag.Bool("skip-cdn", false, "Don't crawl the hosts in scope served by known CDNs, E.g. CloudFront or Akamai, found by their names or the names they are an alias of. They are reported as [cdn] and their urls are still shown.")
+	noTrapDetection := flag.Bool("no-trap-detection", false, "Follow urls that look like crawl traps, such as repeating path segments (/a/b/a/b/a/b), calendars linking to ever later dates and ever-growing query strings. By default they are reported as [trap] and not followed.")
+	maxLinksPerPage := flag.Int("max-links-per-page", 0, "Maximum number of links followed from a single page, so index pages with thousands of links don't take over the crawl. The other links are still printed. 0 for no limit.")
+	maxTotalRequests := flag.Int("max-total-requests", 0, "Maximum number of requests for the whole run, split fairly between the urls from stdin, counting every request sent, E.g. for robots.txt, -head-first, -check-links and -report favicons. 0 for no limit.")
+	cacheDir := flag.String("cache", "", "Directory to cache responses in, so repeated runs reuse them instead of requesting the pages again. Redirects and TLS certificates are only seen when a page is first requested. E.g. -cache .hakcache")
+	validatorsFile := flag.String("validators", "", "File to keep the ETag and Last-Modified headers of pages in between runs. Pages that haven't changed since the previous run aren't parsed again. E.g. -validators validators.json")
+	redisURL := flag.String("redis", "", "Share the visited set and cookies with other instances through Redis, so they don't request the same pages. The work isn't split between them, each crawls the urls it is given. E.g. -redis redis://localhost:6379/0")
+	redisRun := flag.String("redis-run", "", "ID of the run whose visited set is shared through Redis, printed at the start of a run without one. Other instances join the run with it, E.g. -redis-run 5f2b9c1e")
+	redisPrefix := flag.String("redis-prefix", "hakrawler", "Prefix for the keys stored in Redis.")
+	redisTTL := flag.Duration("redis-ttl", 24*time.Hour, "Time the keys stored in Redis are kept after the last crawl writing them. 0 to keep them until they are deleted, E.g. with redis-cli --scan --pattern 'hakrawler:*' | xargs redis-cli del")
+	wordlistFile := flag.String("wordlist", "", "Write the path segments, file names and parameter names of the urls found to a deduplicated wordlist. E.g. -wordlist words.txt")
+	sortOutput := flag.Bool("sort", false, "Print the urls found for each url from stdin sorted by host, then path, once its crawl is done, rather than as they are found.")
+	showSeed := flag.Bool("show-seed", false, "Show the url from stdin that led to each url found.")
+	print0 := flag.Bool("print0", false, "End each line of output with a NUL byte instead of a newline, E.g. for xargs -0.")
+	showStats := flag.Bool("stats", false, "Print a summary to stderr at the end of the run: urls found per source, requests, errors, duration and counts per url from stdin.")
+	noColor := flag.Bool("no-color", false, "Disable colored output. Colors are only used when stdout is a terminal.")
+	sitemapFile := flag.String("export-sitemap", "", "Write the in scope pages visited to a sitemap.xml file: the links to pages other than scripts, styles, media and those matching -no-visit. With several hosts, each gets its own file named after it. E.g. -export-sitemap sitemap.xml")
+	showTree := flag.Bool("tree", false, "Once the crawl is done, also print the paths of the urls found as an indented directory tree per host, for reviewing large results at a glance.")
+	graphFile := flag.String("graph", "", "Write which pages link to which urls to a file, as GraphML if it ends in .graphml and as Graphviz DOT otherwise, E.g. for Gephi or dot -Tsvg. E.g. -graph links.dot")
+	burpFile := flag.String("burp", "", "Write the requests of the urls and forms found to a file in the XML format of Burp's \"Save items\", with the -h and -H headers. E.g. -burp items.xml")
+	zapContextFile := flag.String("zap-context", "", "Write a ZAP context including the in scope hosts found to a file, for Import Context in ZAP. E.g. -zap-context hakrawler.context")
+	openAPIDir := flag.String("openapi", "", "Directory to write a draft OpenAPI document of each in scope host to, with the paths, methods and parameters of the urls and forms found. Numeric and UUID path segments become path parameters. E.g. -openapi specs")
+	asn := flag.Bool("asn", false, "With -report, add the AS number, name and prefix of the addresses of each crawled host to the report, tagging those of well known cloud and CDN providers, E.g. aws or gcp. Looked up over DNS with Team Cymru's IP to ASN service.")
+	resolve := flag.Bool("resolve", false, "With -report, resolve each crawled host and add its A and AAAA records to the report.")
+	reportFile := flag.String("report", "", "Write a JSON summary of each crawled host to a file: endpoints, parameters, JavaScript files, forms, third party domains and favicon hash. E.g. -report report.json")
+	notifyFile := flag.String("notify", "", "JSON file with the Slack or Discord webhook, or the Telegram bot token and chat ID, to send the urls found to, and a regex they must match. E.g. {\"slack_webhook\": \"https://hooks.slack.com/services/..\", \"match\": \"/admin|/debug\"}")
+	exportRequests := flag.String("export-requests", "", "Directory to write the in scope urls with parameters and the forms found to, as raw HTTP requests with the -h and -H headers, one file per endpoint and set of parameters. E.g. -export-requests reqs, then sqlmap -r reqs/<file>")
+	execCommand := flag.String("exec", "", "Command to run for each in scope url found, with {url}, {source}, {where} and {seed} replaced by its values. Its output goes to stderr. E.g. -exec 'nuclei -u {url}'")
+	execThreads := flag.Int("exec-threads", 4, "Number of -exec commands to run at a time.")
+	hookCommand := flag.String("hook", "", "Command started once and handed every response as a JSON line on stdin. It answers each with a JSON line of links found and whether to skip the page. E.g. -hook 'python3 hook.py'")
+	esURL := flag.String("es", "", "Elasticsearch index URL to bulk-index results into. E.g. -es http://localhost:9200/hakrawler")
 
 	flag.Parse()
 
 	if *proxy != "" {
 		os.Setenv("PROXY", *proxy)
 	}
+	var err error
 	var proxyURL *url.URL
 	if envProxy := os.Getenv("PROXY"); envProxy != "" {
 		proxyURL, _ = url.Parse(envProxy)
 	}
 
+	// colors would end up as escape codes in files and pipes, and make no sense in JSON. With -o, stdout gets the
+	// results as plain urls and the file gets them without colors.
+	useColor = !*noColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout) && ((*format == "plain" && !*showJson) || *outputFile != "")
+
+	if *print0 {
+		lineEnd = "\x00"
+	}
+
+	var tmpl *template.Template
+	switch *format {
+	case "plain":
+	case "json":
+		*showJson = true
+	case "curl":
+	case "template":
+		if *templateText == "" {
+			fmt.Fprintln(os.Stderr, "Error parsing template: -format template requires -template")
+			os.Exit(1)
+		}
+		tmpl, err = template.New("result").Parse(*templateText)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error parsing template:", err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "Unknown output format:", *format)
+		os.Exit(1)
+	}
+	if *strategy != "" && *strategy != crawler.StrategyBreadth && *strategy != crawler.StrategyDepth {
+		fmt.Fprintln(os.Stderr, "Unknown crawl strategy:", *strategy)
+		os.Exit(1)
+	}
+
 	// Convert the headers input to a usable map (or die trying)
-	err := parseHeaders(*rawHeaders)
+	err = parseHeaders(*rawHeaders)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "Error parsing headers:", err)
 		os.Exit(1)
 	}
 
+	if *headersFile != "" {
+		fileHeaders, err := readHeadersFile(*headersFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error parsing headers file:", err)
+			os.Exit(1)
+		}
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		for name, value := range fileHeaders {
+			if _, ok := headers[name]; !ok {
+				headers[name] = value
+			}
+		}
+	}
+
+	// a raw request replaces stdin, its headers are sent with every request unless -h overrides them
+	var request *rawRequest
+	if *requestFile != "" {
+		request, err = readRequestFile(*requestFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error parsing request file:", err)
+			os.Exit(1)
+		}
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		for name, value := range request.Headers {
+			if _, ok := headers[name]; !ok {
+				headers[name] = value
+			}
+		}
+	}
+
+	var hostHeaders []crawler.HostHeader
+	if *headerConfig != "" {
+		hostHeaders, err = parseHeaderConfig(*headerConfig)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error parsing header config:", err)
+			os.Exit(1)
+		}
+	}
+
+	filterSizes, err := parseSizes(*filterSize)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error parsing -filter-size:", err)
+		os.Exit(1)
+	}
+	matchSizes, err := parseSizes(*matchSize)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error parsing -match-size:", err)
+		os.Exit(1)
+	}
+
+	limitRules, err := parseLimitRules(*limits)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error parsing limits:", err)
+		os.Exit(1)
+	}
+
+	var previous map[string]bool
+	if *diffFile != "" {
+		previous, err = loadPreviousURLs(*diffFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error reading previous results:", err)
+			os.Exit(1)
+		}
+	}
+
+	var es *esSink
+	if *esURL != "" {
+		es, err = newESSink(*esURL)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error parsing Elasticsearch URL:", err)
+			os.Exit(1)
+		}
+	}
+
+	var exporter *requestExporter
+	if *exportRequests != "" {
+		exporter, err = newRequestExporter(*exportRequests)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error creating export directory:", err)
+			os.Exit(1)
+		}
+	}
+
+	var commands *executor
+	if *execCommand != "" {
+		commands = newExecutor(*execCommand, *execThreads)
+	}
+
+	var notify *notifier
+	if *notifyFile != "" {
+		notify, err = newNotifier(*notifyFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error parsing notify config:", err)
+			os.Exit(1)
+		}
+	}
+
 	// Check for stdin input
 	stat, _ := os.Stdin.Stat()
-	if (stat.Mode() & os.ModeCharDevice) != 0 {
+	if (stat.Mode()&os.ModeCharDevice) != 0 && request == nil {
 		fmt.Fprintln(os.Stderr, "No urls detected. Hint: cat urls.txt | hakrawler")
 		os.Exit(1)
 	}
 
-	results := make(chan string, *threads)
+	baseConfig := crawler.Config{
+		Headers:          headers,
+		HostHeaders:      hostHeaders,
+		Inside:           *inside,
+		MaxDepth:         *depth,
+		Strategy:         *strategy,
+		Prioritize:       *prioritize,
+		MaxLinksPerPage:  *maxLinksPerPage,
+		NoTrapDetection:  *noTrapDetection,
+		SkipCDN:          *skipCDN,
+		DetectWAF:        *detectWAF,
+		RotateUserAgent:  *rotateUserAgent,
+		StripTracking:    *stripTracking,
+		IgnoreCase:       *ignoreCase,
+		IgnoreSlash:      *ignoreSlash,
+		MaxSize:          *maxSize,
+		Binary:           *binary,
+		HeadFirst:        *headFirst,
+		FilterSizes:      filterSizes,
+		MatchSizes:       matchSizes,
+		SubsInScope:      *subsInScope,
+		DisableRedirects: *disableRedirects,
+		Threads:          *threads,
+		LimitRules:       limitRules,
+		Proxy:            proxyURL,
+		IdleConnsPerHost: *idleConns,
+		NoKeepAlive:      *noKeepAlive,
+		TLSTimeout:       *tlsTimeout,
+		Insecure:         *insecure,
+		Timeout:          *timeout,
+		RequestTimeout:   *requestTimeout,
+		CacheDir:         *cacheDir,
+		TargetIP:         *targetIP,
+		Subdomains:       *showSubdomains,
+		CORSOrigin:       *corsOrigin,
+		Redirects:        *showRedirects || *showJson,
+		FinalURLs:        *finalURL,
+		OpenRedirects:    *openRedirects,
+		CheckLinks:       *checkLinks,
+		DirListings:      *dirListings,
+		Routes:           *routes,
+		InlineScripts:    *jsOnly,
+		Errors:           *showJson,
+		RespectRobots:    *respectRobots,
+		RespectNofollow:  *respectNofollow,
+		Polite:           *polite,
+		Pauser:           crawler.NewPauser(),
+	}
+	if request != nil {
+		baseConfig.Method = request.Method
+		baseConfig.Body = request.Body
+	}
+	// an empty list rather than nil, so -no-visit "" visits everything instead of taking the default words
+	baseConfig.NoVisit = []string{}
+	for _, word := range strings.Split(*noVisit, ",") {
+		if word = strings.TrimSpace(word); word != "" {
+			baseConfig.NoVisit = append(baseConfig.NoVisit, word)
+		}
+	}
+	for _, param := range strings.Split(*sessionParams, ",") {
+		if param = strings.TrimSpace(param); param != "" {
+			baseConfig.SessionParams = append(baseConfig.SessionParams, param)
+		}
+	}
+	if *grep != "" {
+		baseConfig.Grep, err = regexp.Compile(*grep)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error parsing grep regex:", err)
+			os.Exit(1)
+		}
+	}
+
+	if *maxBandwidth != "" {
+		rate, err := parseBandwidth(*maxBandwidth)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error parsing max bandwidth:", err)
+			os.Exit(1)
+		}
+		baseConfig.Bandwidth = crawler.NewBandwidthLimit(rate)
+	}
+	if *dnsTTL > 0 {
+		baseConfig.DNSCache = crawler.NewDNSCache(*dnsTTL)
+	}
+	if *unique {
+		baseConfig.Unique = crawler.NewURLSet()
+	}
+	if *awsSigV4 != "" {
+		sign, err := awsSigner(*awsSigV4)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error parsing aws-sigv4:", err)
+			os.Exit(1)
+		}
+		baseConfig.Middleware = append(baseConfig.Middleware, sign)
+	}
+	if *ntlm != "" {
+		baseConfig.NTLM, err = parseNTLM(*ntlm)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error parsing ntlm:", err)
+			os.Exit(1)
+		}
+	}
+	if *oauth2File != "" {
+		token, err := newOAuth2Token(*oauth2File, proxyURL, *insecure)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error getting OAuth2 token:", err)
+			os.Exit(1)
+		}
+		baseConfig.Middleware = append(baseConfig.Middleware, token.Authorize)
+	}
+	if *hookCommand != "" {
+		baseConfig.Hook, err = crawler.NewResponseHook(*hookCommand)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error starting hook:", err)
+			os.Exit(1)
+		}
+	}
+	if *proxyFile != "" {
+		proxies, err := readProxyFile(*proxyFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error reading proxy file:", err)
+			os.Exit(1)
+		}
+		baseConfig.Proxies = crawler.NewProxyRotation(proxies)
+	}
+	if *breaker > 0 {
+		baseConfig.Breaker = crawler.NewCircuitBreaker(*breaker, *breakerCooldown)
+	}
+	if *cookies {
+		baseConfig.Cookies = crawler.NewCookieReport()
+	}
+	if *cors || *corsOrigin != "" {
+		baseConfig.CORS = crawler.NewCORSReport()
+	}
+	if *excludeSubs != "" {
+		baseConfig.ExcludeSubs = strings.Split(*excludeSubs, ",")
+	}
+	if *paths != "" {
+		for _, prefix := range strings.Split(*paths, ",") {
+			if prefix = strings.TrimSpace(prefix); prefix != "" {
+				if !strings.HasPrefix(prefix, "/") {
+					prefix = "/" + prefix
+				}
+				baseConfig.Paths = append(baseConfig.Paths, prefix)
+			}
+		}
+	}
+	handlePauseSignals(baseConfig.Pauser)
+
+	if *pprofAddr != "" {
+		if err := startPprof(*pprofAddr); err != nil {
+			fmt.Fprintln(os.Stderr, "Error starting pprof:", err)
+			os.Exit(1)
+		}
+	}
+
+	if *redisURL != "" {
+		// each run visits the pages again, rather than skipping those visited by an earlier one
+		if *redisRun == "" {
+			id := make([]byte, 4)
+			if _, err := rand.Read(id); err != nil {
+				fmt.Fprintln(os.Stderr, "Error generating the Redis run ID:", err)
+				os.Exit(1)
+			}
+			*redisRun = hex.EncodeToString(id)
+			log.Println("[redis] run " + *redisRun + ", join it with -redis-run " + *redisRun)
+		}
+		store := &crawler.RedisStorage{Address: *redisURL, Prefix: *redisPrefix, Run: *redisRun, TTL: *redisTTL}
+		if err := store.Init(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error connecting to Redis:", err)
+			os.Exit(1)
+		}
+		baseConfig.Storage = store
+	}
+
+	if *validatorsFile != "" {
+		baseConfig.Validators, err = crawler.LoadValidators(*validatorsFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error loading validators:", err)
+			os.Exit(1)
+		}
+	}
+
+	// bound the whole run, if -max-runtime is specified
+	runCtx, cancelRun := context.Background(), context.CancelFunc(func() {})
+	if *maxRuntime > 0 {
+		runCtx, cancelRun = context.WithTimeout(runCtx, *maxRuntime)
+	}
+	defer cancelRun()
+	baseConfig.Context = runCtx
+
+	// get each line of stdin, push it to the seeds channel unless it was already seen. With a request budget, stdin is
+	// read up front so the budget can be split fairly between all of the seeds. In monitor mode, it is read up front so
+	// the seeds can be pushed again every interval.
+	var input io.Reader = os.Stdin
+	if request != nil {
+		input = strings.NewReader(request.URL)
+	}
+	s := bufio.NewScanner(input)
+	seen := make(map[string]bool)
+	var lines []string
+	if *maxTotalRequests > 0 || *monitor {
+		for s.Scan() {
+			if isNewSeed(seen, s.Text(), *dedupeScheme) {
+				lines = append(lines, s.Text())
+			}
+		}
+	}
+	seeds := make(chan string)
 	go func() {
-		// get each line of stdin, push it to the work channel
-		s := bufio.NewScanner(os.Stdin)
+		for _, line := range lines {
+			seeds <- line
+		}
+		for cycle := 2; *monitor; cycle++ {
+			select {
+			case <-time.After(*interval):
+			case <-runCtx.Done():
+				close(seeds)
+				return
+			}
+			log.Println("[monitor] starting cycle " + strconv.Itoa(cycle))
+			for _, line := range lines {
+				select {
+				case seeds <- line:
+				case <-runCtx.Done():
+					close(seeds)
+					return
+				}
+			}
+		}
 		for s.Scan() {
-			url := s.Text()
-			hostname, err := extractHostname(url)
+			if isNewSeed(seen, s.Text(), *dedupeScheme) {
+				seeds <- s.Text()
+			}
+		}
+		if err := s.Err(); err != nil {
+			fmt.Fprintln(os.Stderr, "reading standard input:", err)
+		}
+		close(seeds)
+	}()
+
+	var stats *runStats
+	if *showStats {
+		stats = newRunStats()
+	}
+
+	// third party reports are collected per seed and printed once all urls have been crawled, i.e. after results is closed
+	var reports []thirdPartyReport
+
+	// what is left of -max-total-requests, read once results is closed to cap the requests made after the crawls
+	remaining := *maxTotalRequests
+	results := make(chan crawler.Result, *threads)
+	go func() {
+		defer close(results)
+		seedsLeft := len(lines)
+		for {
+			var url string
+			select {
+			case seed, ok := <-seeds:
+				if !ok {
+					return
+				}
+				url = seed
+			case <-runCtx.Done():
+				log.Println("[max-runtime] reached, skipping the remaining urls")
+				return
+			}
+
+			// each seed gets an equal share of what is left of the budget, so requests unused by one seed go to the next
+			share := 0
+			if *maxTotalRequests > 0 {
+				// in monitor mode, what is left of the budget is split between the seeds of the next cycle
+				if seedsLeft == 0 {
+					seedsLeft = len(lines)
+				}
+				share = remaining / seedsLeft
+				if share == 0 && remaining > 0 {
+					share = 1
+				}
+				seedsLeft--
+			}
+
+			// JSON lines carry the url with settings of its own
+			target := baseConfig
+			if isTargetLine(url) {
+				line := url
+				var err error
+				url, target, err = parseTargetLine(line, baseConfig)
+				if err != nil {
+					if *showJson {
+						printError(os.Stderr, crawler.Result{Source: "error", URL: line, Error: err.Error(), Kind: "input"})
+					} else {
+						log.Println("Error parsing JSON target:", err)
+					}
+					continue
+				}
+			}
+
+			// the probe of bare domains takes from the budget of the crawl
+			var budget *crawler.Budget
+			if *maxTotalRequests > 0 {
+				if share == 0 {
+					log.Println("[max-total-requests] reached, skipping " + url)
+					continue
+				}
+				budget = crawler.NewBudget(share)
+			}
+
+			// bare domains are crawled over https, falling back to http if the host can't be reached over https
+			if isBareDomain(url) {
+				transport := newTransport(proxyURL, target.Insecure)
+				// the probe reaches the same server as the crawl
+				if target.TargetIP != "" {
+					connectTo(transport, url, target.TargetIP)
+				}
+				probed, err := probeScheme(url, target.Headers, budget.Transport(transport))
+				if err != nil {
+					if *showJson {
+						printError(os.Stderr, crawler.Result{Source: "error", URL: url, Error: err.Error(), Kind: crawler.ErrorKind(err)})
+					} else {
+						log.Println("Error probing "+url+":", err)
+					}
+					continue
+				}
+				url = probed
+			}
+
+			// with a target IP, the seed may be given as the IP, so it is requested by the virtual host in the Host header
+			if *targetIP != "" {
+				url = virtualHostURL(url, target.Headers["Host"])
+			}
+
+			config, err := targetConfig(target, url)
 			if err != nil {
-				log.Println("Error parsing URL:", err)
+				if *showJson {
+					printError(os.Stderr, crawler.Result{Source: "error", URL: url, Error: err.Error(), Kind: "input"})
+				} else {
+					log.Println("Error parsing URL:", err)
+				}
 				continue
 			}
 
-			allowed_domains := []string{hostname}
-			// if "Host" header is set, append it to allowed domains
-			if headers != nil {
-				if val, ok := headers["Host"]; ok {
-					allowed_domains = append(allowed_domains, val)
+			config.Budget = budget
+
+			if *thirdParty {
+				config.ThirdParty = crawler.NewThirdPartyDomains()
+			}
+
+			// results are exported with the headers of the seed they were found from
+			seedHeaders.Store(url, config.Headers)
+
+			crawl := func(results chan<- crawler.Result) {
+				if stats != nil {
+					stats.crawl(url, config, results)
+				} else {
+					crawler.Crawl(url, config, results)
 				}
 			}
+			if *sortOutput {
+				sortedCrawl(crawl, results)
+			} else {
+				crawl(results)
+			}
 
-			crawler.Crawl(url, headers, allowed_domains, *inside, *depth, *maxSize, *subsInScope, *disableRedirects, *threads, proxyURL, *insecure, *timeout, hostname, *showSource, *showWhere, *showJson, results)
+			if config.ThirdParty != nil {
+				reports = append(reports, thirdPartyReport{Seed: url, Domains: config.ThirdParty.Domains()})
+			}
 
+			if budget != nil {
+				remaining -= budget.Used()
+			}
 		}
-		if err := s.Err(); err != nil {
-			fmt.Fprintln(os.Stderr, "reading standard input:", err)
-		}
-		close(results)
 	}()
 
 	w := bufio.NewWriter(os.Stdout)
 	defer w.Flush()
 
+	// with -o, the file gets the results in the output format and stdout gets them as plain urls
+	var file *bufio.Writer
+	if *outputFile != "" {
+		f, err := os.Create(*outputFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error creating output file:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		file = bufio.NewWriter(f)
+		defer file.Flush()
+	}
+
+	extensions := make(map[string]bool)
+	for _, ext := range strings.Split(*matchExt, ",") {
+		if ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), ".")); ext != "" {
+			extensions[ext] = true
+		}
+	}
+
+	var words *wordlist
+	if *wordlistFile != "" {
+		words = newWordlist()
+	}
+
+	var hosts *report
+	if *reportFile != "" {
+		hosts = newReport(*resolve, *asn)
+	}
+
+	var spec *apiSpec
+	if *openAPIDir != "" {
+		spec = newAPISpec()
+	}
+
+	var pages *sitemap
+	if *sitemapFile != "" {
+		pages = newSitemap(baseConfig.NoVisit)
+	}
+
+	var graph *linkGraph
+	if *graphFile != "" {
+		graph = newLinkGraph()
+	}
+
+	var tree *pathTree
+	if *showTree {
+		tree = newPathTree()
+	}
+
+	var burp *burpSiteMap
+	if *burpFile != "" {
+		burp = newBurpSiteMap()
+	}
+
+	var zap *zapContext
+	if *zapContextFile != "" {
+		zap = newZAPContext()
+	}
+
 	urlsFound := false
-	if *unique {
-		for res := range results {
-			if isUnique(res) {
-				fmt.Fprintln(w, res)
+	for res := range results {
+		if stats != nil && res.Source != "error" {
+			stats.found(res)
+		}
+		if res.Source == "error" {
+			printError(os.Stderr, res)
+			continue
+		}
+		if words != nil && res.Source != "subdomain" {
+			words.add(res.URL)
+		}
+		if hosts != nil {
+			hosts.add(res)
+		}
+		if spec != nil {
+			spec.add(res)
+		}
+		if pages != nil {
+			pages.add(res)
+		}
+		if graph != nil {
+			graph.add(res)
+		}
+		if tree != nil {
+			tree.add(res)
+		}
+		if burp != nil {
+			burp.add(res)
+		}
+		if zap != nil {
+			zap.add(res)
+		}
+		if len(extensions) > 0 && !extensions[extension(res.URL)] {
+			continue
+		}
+		if previous[res.URL] {
+			continue
+		}
+		// each cycle of monitor mode only shows what wasn't seen by the cycles before
+		if *monitor && !isUnique("monitor "+res.Source+" "+res.URL) {
+			continue
+		}
+		if *showSubdomains {
+			if res.Source == "subdomain" && isUnique("subdomain "+res.URL) {
+				printLine(w, res.URL)
 				urlsFound = true
 			}
+			continue
+		}
+		if res.Source == "redirect" && !*showRedirects && !*showJson && !*disableRedirects {
+			continue
+		}
+		if *queryURLs && !hasQuery(res.URL) {
+			continue
+		}
+		if *jsOnly && (!isJavaScript(res) || !isUnique("js "+hostAndPath(res.URL))) {
+			continue
+		}
+		formatted := func() (string, bool) {
+			if tmpl != nil {
+				var b strings.Builder
+				if err := tmpl.Execute(&b, res); err != nil {
+					log.Println("Error rendering template:", err)
+					return "", false
+				}
+				return b.String(), true
+			} else if *format == "curl" {
+				return curlCommand(res, headersFor(res.Seed), *insecure)
+			}
+			line := formatResult(res, *showSource, *showWhere, *showSeed, *showJson)
+			if *showScope && !*showJson && res.Scope != "" {
+				line = scopeTag(res.Scope) + " " + line
+			}
+			return line, true
+		}
+		var line string
+		var ok bool
+		if file != nil {
+			withoutColor(func() { line, ok = formatted() })
+		} else {
+			line, ok = formatted()
+		}
+		if !ok {
+			continue
+		}
+		if file != nil {
+			printLine(file, line)
+			line = formatResult(res, *showSource, *showWhere, *showSeed, false)
+			if *showScope && res.Scope != "" {
+				line = scopeTag(res.Scope) + " " + line
+			}
+		}
+		printLine(w, line)
+		if *monitor {
+			w.Flush()
+			if file != nil {
+				file.Flush()
+			}
+		}
+		if es != nil {
+			es.Add(res)
+		}
+		if notify != nil {
+			notify.Add(res)
+		}
+		if commands != nil {
+			commands.Add(res)
+		}
+		if exporter != nil {
+			exporter.Add(res)
 		}
-	}
-	// if the first loop ran it drained the results channel, so this loop has nothing left to do
-	for res := range results {
-		fmt.Fprintln(w, res)
 		urlsFound = true
 	}
 
+	// the reports are written like the results, plain on stdout when there is an output file
+	outputs := []io.Writer{w}
+	if file != nil {
+		outputs = []io.Writer{file, w}
+	}
+	for i, out := range outputs {
+		asJson := *showJson && i == 0
+		printReports := func() {
+			for _, report := range reports {
+				printThirdPartyReport(out, report, asJson)
+			}
+			if baseConfig.CORS != nil {
+				for _, entry := range baseConfig.CORS.Entries() {
+					printCORSEntry(out, entry, asJson)
+				}
+			}
+
+			if baseConfig.Cookies != nil {
+				for _, entry := range baseConfig.Cookies.Entries() {
+					printCookieEntry(out, entry, asJson)
+				}
+			}
+		}
+		if file != nil && i == 0 {
+			withoutColor(printReports)
+		} else {
+			printReports()
+		}
+	}
+
+	if tree != nil {
+		tree.print(w)
+	}
+
+	if baseConfig.Validators != nil {
+		if err := baseConfig.Validators.Save(*validatorsFile); err != nil {
+			log.Println("Error saving validators:", err)
+		}
+	}
+
+	if words != nil {
+		if err := words.write(*wordlistFile); err != nil {
+			log.Println("Error writing wordlist:", err)
+		}
+	}
+
+	if hosts != nil {
+		var transport http.RoundTripper = newTransport(proxyURL, *insecure)
+		if *maxTotalRequests > 0 {
+			transport = crawler.NewBudget(remaining).Transport(transport)
+		}
+		if err := hosts.write(*reportFile, &http.Client{Transport: transport, Timeout: 10 * time.Second}); err != nil {
+			log.Println("Error writing report:", err)
+		}
+	}
+
+	if spec != nil {
+		if err := spec.write(*openAPIDir); err != nil {
+			log.Println("Error writing OpenAPI documents:", err)
+		}
+	}
+
+	if pages != nil {
+		if err := pages.write(*sitemapFile); err != nil {
+			log.Println("Error writing sitemap:", err)
+		}
+	}
+
+	if graph != nil {
+		if err := graph.write(*graphFile); err != nil {
+			log.Println("Error writing link graph:", err)
+		}
+	}
+
+	if burp != nil {
+		if err := burp.write(*burpFile); err != nil {
+			log.Println("Error writing Burp items:", err)
+		}
+	}
+
+	if zap != nil {
+		if err := zap.write(*zapContextFile); err != nil {
+			log.Println("Error writing ZAP context:", err)
+		}
+	}
+
+	if notify != nil {
+		notify.Close()
+	}
+
+	if commands != nil {
+		commands.Wait()
+	}
+
+	if baseConfig.Hook != nil {
+		if err := baseConfig.Hook.Close(); err != nil {
+			log.Println("Error stopping hook:", err)
+		}
+	}
+
+	if es != nil {
+		if err := es.Flush(); err != nil {
+			log.Println("Error indexing results:", err)
+		}
+	}
+
+	if stats != nil {
+		stats.print(os.Stderr, *showJson)
+	}
+
 	if !urlsFound {
 		fmt.Fprintln(os.Stderr, "No URLs were found. This usually happens when a domain is specified (https://example.com), but it redirects to a subdomain (https://www.example.com). The subdomain is not included in the scope, so the no URLs are printed. In order to overcome this, either specify the final URL in the redirect chain or use the -subs option to include subdomains.")
 	}
 
 }
 
+// headersFor returns the headers seed is crawled with, those of -h and -H unless it is a JSON target with headers
+func headersFor(seed string) map[string]string {
+	if h, ok := seedHeaders.Load(seed); ok {
+		return h.(map[string]string)
+	}
+	return headers
+}
+
 // parseHeaders does validation of headers input and saves it to a formatted map.
 func parseHeaders(rawHeaders string) error {
 	if rawHeaders != "" {
@@ -122,20 +991,279 @@ func parseHeaders(rawHeaders string) error {
 		headers = make(map[string]string)
 		rawHeaders := strings.Split(rawHeaders, ";;")
 		for _, header := range rawHeaders {
-			var parts []string
-			if strings.Contains(header, ": ") {
-				parts = strings.SplitN(header, ": ", 2)
-			} else if strings.Contains(header, ":") {
-				parts = strings.SplitN(header, ":", 2)
-			} else {
-				continue
+			if name, value, ok := splitHeader(header); ok {
+				headers[name] = value
 			}
-			headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
 		}
 	}
 	return nil
 }
 
+// splitHeader splits a "Name: value" header into its name and value
+func splitHeader(header string) (string, string, bool) {
+	var parts []string
+	if strings.Contains(header, ": ") {
+		parts = strings.SplitN(header, ": ", 2)
+	} else if strings.Contains(header, ":") {
+		parts = strings.SplitN(header, ":", 2)
+	} else {
+		return "", "", false
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), true
+}
+
+// readHeadersFile reads headers from a file with one "Name: value" per line. Empty lines and lines starting with # are skipped.
+func readHeadersFile(filename string) (map[string]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fileHeaders := make(map[string]string)
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := splitHeader(line)
+		if !ok {
+			return nil, fmt.Errorf("line %d not formatted properly (no colon to separate header and value)", n)
+		}
+		fileHeaders[name] = value
+	}
+	return fileHeaders, s.Err()
+}
+
+// readProxyFile reads proxy URLs from a file with one per line. Empty lines and lines starting with # are skipped.
+func readProxyFile(filename string) ([]*url.URL, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var proxies []*url.URL
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		u, err := url.Parse(line)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("line %d not formatted properly (expected a url such as http://127.0.0.1:8080)", n)
+		}
+		proxies = append(proxies, u)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(proxies) == 0 {
+		return nil, errors.New("no proxies in " + filename)
+	}
+	return proxies, nil
+}
+
+// parseHeaderConfig reads the headers to send to specific hosts from a file with one "host Name: value" per line,
+// where host may be *.example.com for example.com and its subdomains. Empty lines and lines starting with # are skipped.
+func parseHeaderConfig(filename string) ([]crawler.HostHeader, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var hostHeaders []crawler.HostHeader
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d not formatted properly (expected \"host Name: value\")", n)
+		}
+		name, value, ok := splitHeader(fields[1])
+		if !ok {
+			return nil, fmt.Errorf("line %d not formatted properly (no colon to separate header and value)", n)
+		}
+		hostHeaders = append(hostHeaders, crawler.HostHeader{Host: fields[0], Name: name, Value: value})
+	}
+	return hostHeaders, s.Err()
+}
+
+// isNewSeed returns whether a line of stdin wasn't seen before, recording it in seen. With collapseScheme, http:// and
+// https:// variants of the same url count as the same seed.
+func isNewSeed(seen map[string]bool, line string, collapseScheme bool) bool {
+	key := strings.TrimSpace(line)
+	if collapseScheme {
+		lower := strings.ToLower(key)
+		for _, scheme := range []string{"http://", "https://"} {
+			if strings.HasPrefix(lower, scheme) {
+				key = key[len(scheme):]
+				break
+			}
+		}
+	}
+	if seen[key] {
+		return false
+	}
+	seen[key] = true
+	return true
+}
+
+// parseLimitRules parses comma separated glob=parallelism or glob=parallelism/delay limits
+func parseLimitRules(raw string) ([]crawler.LimitRule, error) {
+	var rules []crawler.LimitRule
+	for _, limit := range strings.Split(raw, ",") {
+		if limit = strings.TrimSpace(limit); limit == "" {
+			continue
+		}
+		parts := strings.SplitN(limit, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.New(limit + " not formatted properly (expected glob=parallelism or glob=parallelism/delay)")
+		}
+		rule := crawler.LimitRule{DomainGlob: parts[0]}
+		values := strings.SplitN(parts[1], "/", 2)
+		parallelism, err := strconv.Atoi(values[0])
+		if err != nil || parallelism < 0 {
+			return nil, errors.New(limit + " has an invalid parallelism")
+		}
+		rule.Parallelism = parallelism
+		if len(values) == 2 {
+			if rule.Delay, err = time.ParseDuration(values[1]); err != nil {
+				return nil, errors.New(limit + " has an invalid delay")
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// parseSizes parses comma separated sizes and ranges of sizes, E.g. 1234, 100-200 or 1000- for 1000 and more
+func parseSizes(raw string) ([]crawler.SizeRange, error) {
+	var ranges []crawler.SizeRange
+	for _, size := range strings.Split(raw, ",") {
+		if size = strings.TrimSpace(size); size == "" {
+			continue
+		}
+		bounds := strings.SplitN(size, "-", 2)
+		min, err := strconv.Atoi(bounds[0])
+		if err != nil || min < 0 {
+			return nil, errors.New(size + " is not a valid size or range of sizes")
+		}
+		r := crawler.SizeRange{Min: min, Max: min}
+		if len(bounds) == 2 {
+			r.Max = -1
+			if bounds[1] != "" {
+				if r.Max, err = strconv.Atoi(bounds[1]); err != nil || r.Max < min {
+					return nil, errors.New(size + " is not a valid size or range of sizes")
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// parseBandwidth parses a rate such as 5MB/s, 500KB/s or 1048576 into bytes per second
+func parseBandwidth(raw string) (int64, error) {
+	value := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(raw)), "/S")
+	value = strings.TrimSuffix(value, "B")
+	multiplier := 1.0
+	for suffix, m := range map[string]float64{"K": 1 << 10, "M": 1 << 20, "G": 1 << 30} {
+		if strings.HasSuffix(value, suffix) {
+			value = strings.TrimSuffix(value, suffix)
+			multiplier = m
+		}
+	}
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate*multiplier < 1 {
+		return 0, errors.New(raw + " is not a valid bandwidth")
+	}
+	return int64(rate * multiplier), nil
+}
+
+// awsSigner returns the middleware signing requests for a -aws-sigv4 region/service, with the credentials from the
+// environment
+func awsSigner(raw string) (crawler.RequestMiddleware, error) {
+	parts := strings.SplitN(raw, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, errors.New(raw + " is not a region/service, E.g. us-east-1/execute-api")
+	}
+	credentials := crawler.AWSCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return nil, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	return crawler.SigV4(credentials, parts[0], parts[1]), nil
+}
+
+// parseNTLM parses the -ntlm account, as domain\user:password or user:password
+func parseNTLM(raw string) (*crawler.NTLMCredentials, error) {
+	parts := strings.SplitN(raw, ":", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return nil, errors.New("expected domain\\user:password or user:password")
+	}
+	credentials := &crawler.NTLMCredentials{User: parts[0], Password: parts[1]}
+	if i := strings.Index(parts[0], "\\"); i >= 0 {
+		credentials.Domain, credentials.User = parts[0][:i], parts[0][i+1:]
+	}
+	return credentials, nil
+}
+
+// newOAuth2Token reads the -oauth2 grant in filename and gets its first token, through the proxy if any
+func newOAuth2Token(filename string, proxyURL *url.URL, insecure bool) (*crawler.OAuth2Token, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var config crawler.OAuth2Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	return crawler.NewOAuth2Token(config, &http.Client{Transport: newTransport(proxyURL, insecure), Timeout: 30 * time.Second})
+}
+
+// targetConfig returns a copy of base with the scope set up for crawling url
+func targetConfig(base crawler.Config, url string) (crawler.Config, error) {
+	hostname, err := extractHostname(url)
+	if err != nil {
+		return base, err
+	}
+
+	allowed_domains := []string{hostname}
+	// if "Host" header is set, append it to allowed domains
+	if base.Headers != nil {
+		if val, ok := base.Headers["Host"]; ok {
+			allowed_domains = append(allowed_domains, val)
+		}
+	}
+
+	base.Hostname = hostname
+	base.AllowedDomains = allowed_domains
+	return base, nil
+}
+
+// virtualHostURL returns seed with its host replaced by host, keeping the port of seed if host has none
+func virtualHostURL(seed string, host string) string {
+	u, err := url.Parse(seed)
+	if err != nil || host == "" || !u.IsAbs() {
+		return seed
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil && u.Port() != "" {
+		host = net.JoinHostPort(host, u.Port())
+	}
+	u.Host = host
+	return u.String()
+}
+
 // extractHostname() extracts the hostname from a URL and returns it
 func extractHostname(urlString string) (string, error) {
 	u, err := url.Parse(urlString)
@@ -146,6 +1274,179 @@ func extractHostname(urlString string) (string, error) {
 	return u.Hostname(), nil
 }
 
+// thirdPartyReport lists the out of scope domains referenced while crawling a seed
+type thirdPartyReport struct {
+	Seed    string
+	Domains map[string][]string
+}
+
+// printThirdPartyReport writes the report as a JSON line, or as one line per domain with the sources it was referenced from
+func printThirdPartyReport(w io.Writer, report thirdPartyReport, showJson bool) {
+	if showJson {
+		bytes, _ := json.Marshal(report)
+		printLine(w, string(bytes))
+		return
+	}
+	domains := make([]string, 0, len(report.Domains))
+	for domain := range report.Domains {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	for _, domain := range domains {
+		printLine(w, tag("third-party")+" ["+report.Seed+"] "+domain+" ("+strings.Join(report.Domains[domain], ", ")+")")
+	}
+}
+
+// errorRecord is the JSON form of a request that failed, told apart from results by its type
+type errorRecord struct {
+	Type  string `json:"type"`
+	Kind  string `json:"kind"`
+	URL   string `json:"url"`
+	Seed  string `json:"seed"`
+	Error string `json:"error"`
+}
+
+// printError writes a failed request as a JSON line
+func printError(w io.Writer, res crawler.Result) {
+	bytes, _ := json.Marshal(errorRecord{Type: "error", Kind: res.Kind, URL: res.URL, Seed: res.Seed, Error: res.Error})
+	fmt.Fprintln(w, string(bytes))
+}
+
+// printCORSEntry writes the CORS headers of an endpoint as a JSON line or a tagged line
+func printCORSEntry(w io.Writer, entry crawler.CORSEntry, showJson bool) {
+	if showJson {
+		bytes, _ := json.Marshal(entry)
+		printLine(w, string(bytes))
+		return
+	}
+	line := tag("cors") + " " + entry.URL + " allow-origin=" + entry.AllowOrigin
+	if entry.AllowCredentials {
+		line += " " + paint(colorYellow, "allow-credentials")
+	}
+	if entry.Reflected {
+		line += " " + paint(colorRed, "reflected")
+	}
+	printLine(w, line)
+}
+
+// printCookieEntry writes a cookie as a JSON line or a tagged line with its attributes
+func printCookieEntry(w io.Writer, entry crawler.CookieEntry, showJson bool) {
+	if showJson {
+		bytes, _ := json.Marshal(entry)
+		printLine(w, string(bytes))
+		return
+	}
+	line := tag("cookie") + " [" + entry.Host + "] " + entry.Name
+	if entry.Secure {
+		line += " secure"
+	} else {
+		line += " " + paint(colorYellow, "insecure")
+	}
+	if entry.HttpOnly {
+		line += " httponly"
+	}
+	if entry.SameSite != "" {
+		line += " samesite=" + entry.SameSite
+	}
+	printLine(w, line)
+}
+
+// formatResult constructs the output line for a result
+func formatResult(res crawler.Result, showSource bool, showWhere bool, showSeed bool, showJson bool) string {
+	result := res.URL
+	// findings are always tagged, so they can be told apart from the plain urls
+	if !showJson {
+		switch res.Source {
+		case "broken":
+			if res.Status != 0 {
+				result = paint(statusColor(res.Status), strconv.Itoa(res.Status)) + " " + result
+			} else {
+				result += " (" + res.Error + ")"
+			}
+			if !showSource {
+				result = tag("broken") + " " + result
+			}
+		case "trap", "cdn", "waf", "truncated", "unchecked":
+			result += " (" + res.Error + ")"
+			if !showSource {
+				result = tag(res.Source) + " " + result
+			}
+		case "grep":
+			result += " " + strconv.Quote(res.Match)
+			if !showSource {
+				result = tag("grep") + " " + result
+			}
+		case "open-redirect", "directory-listing":
+			if !showSource {
+				result = tag(res.Source) + " " + result
+			}
+		}
+	}
+	if res.Source == "redirect" && !showJson {
+		result = strings.Join(res.Redirects, " -> ")
+	} else if res.FinalURL != "" && res.FinalURL != res.URL && !showJson {
+		result += " -> " + res.FinalURL
+	}
+	if showJson {
+		if !showWhere {
+			res.Where = ""
+		}
+		bytes, _ := json.Marshal(res)
+		result = string(bytes)
+	} else if showSource {
+		result = tag(res.Source) + " " + result
+	}
+
+	if showWhere && !showJson {
+		result = "[" + res.Where + "] " + result
+	}
+	if showSeed && !showJson {
+		result = "[" + res.Seed + "] " + result
+	}
+	return result
+}
+
+// extension returns the lower case extension of the supplied url's path, without the dot
+func extension(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimPrefix(path.Ext(u.Path), "."))
+}
+
+// isJavaScript returns whether the result is a JavaScript file
+func isJavaScript(res crawler.Result) bool {
+	if res.Source == "script" || res.Source == "inline" {
+		return true
+	}
+	ext := extension(res.URL)
+	return ext == "js" || ext == "mjs"
+}
+
+// hostAndPath returns the supplied url without its scheme, query string and fragment
+func hostAndPath(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return strings.ToLower(u.Host) + u.Path
+}
+
+// hasQuery returns whether the supplied url has a query string
+func hasQuery(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && u.RawQuery != ""
+}
+
+// lineEnd terminates the lines of output, a NUL byte with -print0
+var lineEnd = "\n"
+
+// printLine writes a line of output
+func printLine(w io.Writer, line string) {
+	io.WriteString(w, line+lineEnd)
+}
+
 // returns whether the supplied url is unique or not
 func isUnique(url string) bool {
 	_, present := sm.Load(url)
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/palaziv/hakrawler/crawler"
+)
+
+// how often the urls found are sent, so long runs and -monitor notify as they go
+const notifyInterval = 10 * time.Second
+
+// longest message sent, Discord's limit being the lowest
+const notifyMessageSize = 1900
+
+// notifyConfig is the -notify file, E.g.
+// {"slack_webhook": "https://hooks.slack.com/services/..", "match": "/admin|/debug"}
+type notifyConfig struct {
+	SlackWebhook   string `json:"slack_webhook"`
+	DiscordWebhook string `json:"discord_webhook"`
+	TelegramToken  string `json:"telegram_token"`
+	TelegramChatID string `json:"telegram_chat_id"`
+	Match          string `json:"match"` // only urls matching this regex are sent, empty for all of them
+}
+
+// notifier sends the urls found to chat webhooks in batches
+type notifier struct {
+	config notifyConfig
+	match  *regexp.Regexp
+	client *http.Client
+
+	mu   sync.Mutex
+	urls []string
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newNotifier reads the config in filename and starts sending batches in the background
+func newNotifier(filename string) (*notifier, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	n := &notifier{client: &http.Client{Timeout: 30 * time.Second}, done: make(chan struct{})}
+	if err := json.Unmarshal(data, &n.config); err != nil {
+		return nil, err
+	}
+	if n.config.SlackWebhook == "" && n.config.DiscordWebhook == "" && (n.config.TelegramToken == "" || n.config.TelegramChatID == "") {
+		return nil, errors.New("no slack_webhook, discord_webhook or telegram_token and telegram_chat_id specified")
+	}
+	if n.config.Match != "" {
+		if n.match, err = regexp.Compile(n.config.Match); err != nil {
+			return nil, err
+		}
+	}
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		ticker := time.NewTicker(notifyInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				n.flush()
+			case <-n.done:
+				n.flush()
+				return
+			}
+		}
+	}()
+	return n, nil
+}
+
+// Add queues the url of a result if it matches
+func (n *notifier) Add(res crawler.Result) {
+	if n.match != nil && !n.match.MatchString(res.URL) {
+		return
+	}
+	n.mu.Lock()
+	n.urls = append(n.urls, res.URL)
+	n.mu.Unlock()
+}
+
+// Close sends the urls still queued
+func (n *notifier) Close() {
+	close(n.done)
+	n.wg.Wait()
+}
+
+// flush sends the queued urls, split into as many messages as needed
+func (n *notifier) flush() {
+	n.mu.Lock()
+	urls := n.urls
+	n.urls = nil
+	n.mu.Unlock()
+	if len(urls) == 0 {
+		return
+	}
+
+	header := "hakrawler found " + strconv.Itoa(len(urls)) + " new urls:"
+	message := header
+	for _, u := range urls {
+		if len(message)+1+len(u) > notifyMessageSize && message != header {
+			n.send(message)
+			message = header
+		}
+		message += "\n" + u
+	}
+	n.send(message)
+}
+
+// send posts a message to every configured service
+func (n *notifier) send(message string) {
+	if n.config.SlackWebhook != "" {
+		n.post("Slack", n.config.SlackWebhook, map[string]string{"text": message})
+	}
+	if n.config.DiscordWebhook != "" {
+		n.post("Discord", n.config.DiscordWebhook, map[string]string{"content": message})
+	}
+	if n.config.TelegramToken != "" && n.config.TelegramChatID != "" {
+		n.post("Telegram", "https://api.telegram.org/bot"+n.config.TelegramToken+"/sendMessage",
+			map[string]string{"chat_id": n.config.TelegramChatID, "text": message})
+	}
+}
+
+func (n *notifier) post(service string, endpoint string, payload map[string]string) {
+	body, _ := json.Marshal(payload)
+	resp, err := n.client.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		msg := err.Error()
+		// the error contains the endpoint, which holds the token for Telegram
+		if n.config.TelegramToken != "" {
+			msg = strings.ReplaceAll(msg, n.config.TelegramToken, "<token>")
+		}
+		log.Println("Error sending notification to "+service+":", msg)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Println("Error sending notification to "+service+": status", resp.Status)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/palaziv/hakrawler/crawler"
+)
+
+// path segments that are most likely identifiers, written as path parameters: numbers, UUIDs and long hex strings
+var idSegmentRegex = regexp.MustCompile(`(?i)^([0-9]+|[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}|[0-9a-f]{24,})$`)
+
+// the parameters of a path template
+var pathParamRegex = regexp.MustCompile(`\{([a-z0-9]+)\}`)
+
+// extensions of files that are not part of an API
+var staticExtensions = map[string]bool{
+	"js": true, "mjs": true, "css": true, "map": true, "png": true, "jpg": true, "jpeg": true, "gif": true, "svg": true,
+	"ico": true, "webp": true, "woff": true, "woff2": true, "ttf": true, "eot": true, "otf": true, "mp4": true,
+	"mp3": true, "webm": true, "pdf": true,
+}
+
+// apiOperation is what was seen of a method on a path
+type apiOperation struct {
+	query map[string]bool
+	form  map[string]bool
+}
+
+// apiSpec collects the endpoints of the in scope hosts found, for -openapi
+type apiSpec struct {
+	origins map[string]map[string]bool                     // host -> scheme, host and port of its urls
+	paths   map[string]map[string]map[string]*apiOperation // host -> path template -> method -> operation
+}
+
+func newAPISpec() *apiSpec {
+	return &apiSpec{
+		origins: make(map[string]map[string]bool),
+		paths:   make(map[string]map[string]map[string]*apiOperation),
+	}
+}
+
+// pathTemplate returns the path of a url with identifiers replaced by parameters, E.g. /users/{id}/orders/{id2}
+func pathTemplate(p string) string {
+	if p == "" {
+		return "/"
+	}
+	segments := strings.Split(p, "/")
+	params := 0
+	for i, segment := range segments {
+		if idSegmentRegex.MatchString(segment) {
+			params++
+			segments[i] = "{id}"
+			if params > 1 {
+				segments[i] = "{id" + strconv.Itoa(params) + "}"
+			}
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// add records the endpoint of a result
+func (s *apiSpec) add(res crawler.Result) {
+	if res.Scope != "in" || crawler.IsFindingSource(res.Source) || isJavaScript(res) || staticExtensions[extension(res.URL)] {
+		return
+	}
+	method, u, body, ok := resultRequest(res)
+	if !ok {
+		return
+	}
+	host := strings.ToLower(u.Hostname())
+	addTo(s.origins, host, u.Scheme+"://"+u.Host)
+
+	template := pathTemplate(u.Path)
+	if s.paths[host] == nil {
+		s.paths[host] = make(map[string]map[string]*apiOperation)
+	}
+	if s.paths[host][template] == nil {
+		s.paths[host][template] = make(map[string]*apiOperation)
+	}
+	method = strings.ToLower(method)
+	operation := s.paths[host][template][method]
+	if operation == nil {
+		operation = &apiOperation{query: make(map[string]bool), form: make(map[string]bool)}
+		s.paths[host][template][method] = operation
+	}
+	for _, name := range paramNames(u.RawQuery) {
+		if name, err := url.QueryUnescape(name); err == nil {
+			operation.query[name] = true
+		}
+	}
+	for _, name := range paramNames(body) {
+		if name, err := url.QueryUnescape(name); err == nil {
+			operation.form[name] = true
+		}
+	}
+}
+
+// document returns the OpenAPI 3 document of a host
+func (s *apiSpec) document(host string) map[string]interface{} {
+	var servers []map[string]string
+	for _, origin := range sorted(s.origins[host]) {
+		servers = append(servers, map[string]string{"url": origin})
+	}
+	stringSchema := map[string]string{"type": "string"}
+
+	paths := make(map[string]interface{})
+	for template, methods := range s.paths[host] {
+		var pathParams []string
+		for _, match := range pathParamRegex.FindAllStringSubmatch(template, -1) {
+			pathParams = append(pathParams, match[1])
+		}
+		item := make(map[string]interface{})
+		for method, operation := range methods {
+			parameters := []map[string]interface{}{}
+			for _, name := range pathParams {
+				parameters = append(parameters, map[string]interface{}{"name": name, "in": "path", "required": true, "schema": stringSchema})
+			}
+			for _, name := range sorted(operation.query) {
+				parameters = append(parameters, map[string]interface{}{"name": name, "in": "query", "schema": stringSchema})
+			}
+			op := map[string]interface{}{
+				"responses": map[string]interface{}{"default": map[string]string{"description": "Not documented"}},
+			}
+			if len(parameters) > 0 {
+				op["parameters"] = parameters
+			}
+			if len(operation.form) > 0 {
+				properties := make(map[string]interface{})
+				for name := range operation.form {
+					properties[name] = stringSchema
+				}
+				op["requestBody"] = map[string]interface{}{
+					"content": map[string]interface{}{
+						"application/x-www-form-urlencoded": map[string]interface{}{
+							"schema": map[string]interface{}{"type": "object", "properties": properties},
+						},
+					},
+				}
+			}
+			item[method] = op
+		}
+		paths[template] = item
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    map[string]string{"title": host, "version": "draft", "description": "Generated by hakrawler from the urls and forms found"},
+		"servers": servers,
+		"paths":   paths,
+	}
+}
+
+// write saves the document of each host to dir, as <host>.json
+func (s *apiSpec) write(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	hosts := make([]string, 0, len(s.paths))
+	for host := range s.paths {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		bytes, err := json.MarshalIndent(s.document(host), "", "  ")
+		if err != nil {
+			return err
+		}
+		name := strings.Trim(unsafeFilenameRegex.ReplaceAllString(host, "_"), "_")
+		if err := os.WriteFile(filepath.Join(dir, name+".json"), append(bytes, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
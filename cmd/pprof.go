@@ -0,0 +1,23 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	_ "net/http/pprof" // registers the /debug/pprof handlers
+)
+
+// startPprof serves the profiling endpoints on addr in the background, E.g. for
+// go tool pprof http://localhost:6060/debug/pprof/heap
+func startPprof(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := http.Serve(listener, nil); err != nil {
+			log.Println("Error serving pprof:", err)
+		}
+	}()
+	return nil
+}
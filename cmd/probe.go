@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// isBareDomain returns whether a url from stdin has no scheme, E.g. example.com or example.com/path
+func isBareDomain(seed string) bool {
+	return seed != "" && !strings.Contains(seed, "://")
+}
+
+// newTransport returns a transport for the requests made outside of crawls, using the proxy and TLS settings
+func newTransport(proxyURL *url.URL, insecure bool) *http.Transport {
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure},
+	}
+	if proxyURL != nil {
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return transport
+}
+
+// connectTo makes transport connect to ip instead of resolving the host of a bare domain seed, like the crawl does with
+// -target-ip
+func connectTo(transport *http.Transport, seed string, ip string) {
+	u, err := url.Parse("http://" + seed)
+	if err != nil {
+		return
+	}
+	host := u.Hostname()
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport.DialContext = func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		if h, port, err := net.SplitHostPort(addr); err == nil && strings.EqualFold(h, host) {
+			addr = net.JoinHostPort(ip, port)
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
+
+// probeScheme returns seed prefixed with https://, or with http:// if the host can't be reached over https. The
+// requests are sent with headers through transport.
+func probeScheme(seed string, headers map[string]string, transport http.RoundTripper) (string, error) {
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   10 * time.Second,
+		// any response means the scheme works, wherever it redirects to
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	var err error
+	for _, scheme := range []string{"https://", "http://"} {
+		var req *http.Request
+		req, err = http.NewRequest(http.MethodHead, scheme+seed, nil)
+		if err != nil {
+			return "", err
+		}
+		for header, value := range headers {
+			req.Header.Set(header, value)
+		}
+		if host := req.Header.Get("Host"); host != "" {
+			req.Host = host
+		}
+		var resp *http.Response
+		resp, err = client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			return scheme + seed, nil
+		}
+	}
+	return "", err
+}
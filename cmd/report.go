@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/palaziv/hakrawler/crawler"
+)
+
+// hostReport is the summary of a crawled host, for -report
+type hostReport struct {
+	Endpoints  int       `json:"endpoints"`
+	Parameters []string  `json:"parameters"`
+	JSFiles    []string  `json:"js_files"`
+	Forms      []string  `json:"forms"`
+	ThirdParty []string  `json:"third_party"`
+	A          []string  `json:"a,omitempty"`
+	AAAA       []string  `json:"aaaa,omitempty"`
+	ASN        []hostASN `json:"asn,omitempty"`
+	WAF        string    `json:"waf,omitempty"` // with -waf, the WAF it is behind
+	// FaviconHash is the Shodan compatible hash of /favicon.ico, E.g. for searching http.favicon.hash:-1234
+	FaviconHash *int32 `json:"favicon_hash,omitempty"`
+}
+
+// report collects what was found on each host. Hosts only count as crawled once a page on them is seen, which may
+// happen after links to them, so everything is kept per host and summarised when the report is written.
+type report struct {
+	resolve    bool       // whether the A and AAAA records of hosts are looked up
+	asn        *asnLookup // looks up the networks of their addresses, nil not to
+	crawled    map[string]bool
+	origins    map[string]string          // host -> scheme, host and port of the first page crawled on it
+	seeds      map[string]string          // host -> seed of the first page crawled on it, whose headers its favicon is fetched with
+	endpoints  map[string]map[string]bool // host -> scheme, host and path of the urls on it
+	parameters map[string]map[string]bool
+	jsFiles    map[string]map[string]bool
+	forms      map[string]map[string]bool
+	referenced map[string]map[string]bool // host of the page -> hosts it links to
+	wafs       map[string]string
+}
+
+func newReport(resolve bool, asn bool) *report {
+	rp := &report{
+		resolve:    resolve || asn,
+		crawled:    make(map[string]bool),
+		origins:    make(map[string]string),
+		seeds:      make(map[string]string),
+		endpoints:  make(map[string]map[string]bool),
+		parameters: make(map[string]map[string]bool),
+		jsFiles:    make(map[string]map[string]bool),
+		forms:      make(map[string]map[string]bool),
+		referenced: make(map[string]map[string]bool),
+		wafs:       make(map[string]string),
+	}
+	if asn {
+		rp.asn = newASNLookup()
+	}
+	return rp
+}
+
+// addTo adds value to the set of host in sets
+func addTo(sets map[string]map[string]bool, host string, value string) {
+	if sets[host] == nil {
+		sets[host] = make(map[string]bool)
+	}
+	sets[host][value] = true
+}
+
+// add records a result
+func (rp *report) add(res crawler.Result) {
+	if res.Source == "waf" {
+		if u, err := url.Parse(res.URL); err == nil {
+			rp.wafs[strings.ToLower(u.Hostname())] = res.Error
+		}
+	}
+	if crawler.IsFindingSource(res.Source) {
+		return
+	}
+	u, err := url.Parse(res.URL)
+	if err != nil || u.Hostname() == "" {
+		return
+	}
+	host := strings.ToLower(u.Hostname())
+	if where, err := url.Parse(res.Where); err == nil && where.Hostname() != "" {
+		page := strings.ToLower(where.Hostname())
+		rp.crawled[page] = true
+		if _, ok := rp.origins[page]; !ok {
+			rp.origins[page] = where.Scheme + "://" + where.Host
+			rp.seeds[page] = res.Seed
+		}
+		addTo(rp.referenced, page, host)
+	}
+
+	addTo(rp.endpoints, host, u.Scheme+"://"+u.Host+u.Path)
+	for name := range u.Query() {
+		addTo(rp.parameters, host, name)
+	}
+	if isJavaScript(res) {
+		addTo(rp.jsFiles, host, res.URL)
+	}
+	if res.Source == "form" {
+		addTo(rp.forms, host, res.URL)
+	}
+}
+
+// sorted returns the members of a set in sorted order, never nil so it is written as [] rather than null
+func sorted(set map[string]bool) []string {
+	values := make([]string, 0, len(set))
+	for value := range set {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// write saves the summary of every crawled host to filename as JSON, fetching their favicons with client
+func (rp *report) write(filename string, client *http.Client) error {
+	hosts := make(map[string]hostReport)
+	for host := range rp.crawled {
+		var thirdParty []string
+		for _, other := range sorted(rp.referenced[host]) {
+			if !rp.crawled[other] {
+				thirdParty = append(thirdParty, other)
+			}
+		}
+		if thirdParty == nil {
+			thirdParty = []string{}
+		}
+		summary := hostReport{
+			Endpoints:  len(rp.endpoints[host]),
+			Parameters: sorted(rp.parameters[host]),
+			JSFiles:    sorted(rp.jsFiles[host]),
+			Forms:      sorted(rp.forms[host]),
+			ThirdParty: thirdParty,
+			WAF:        rp.wafs[host],
+		}
+		if rp.resolve {
+			summary.A, summary.AAAA = resolveHost(host)
+		}
+		if rp.asn != nil {
+			addrs := append(append([]string{}, summary.A...), summary.AAAA...)
+			if net.ParseIP(host) != nil {
+				addrs = []string{host}
+			}
+			for _, addr := range addrs {
+				if network, ok := rp.asn.lookup(addr); ok {
+					summary.ASN = append(summary.ASN, network)
+				}
+			}
+		}
+		if hash, ok := faviconHash(client, rp.origins[host], headersFor(rp.seeds[host])); ok {
+			summary.FaviconHash = &hash
+		}
+		hosts[host] = summary
+	}
+
+	bytes, err := json.MarshalIndent(hosts, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(bytes, '\n'), 0644)
+}
+
+// resolveHost returns the IPv4 and IPv6 addresses of host, none if it is an IP address or can't be resolved
+func resolveHost(host string) ([]string, []string) {
+	if net.ParseIP(host) != nil {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		log.Println("Error resolving host:", err)
+		return nil, nil
+	}
+	var a, aaaa []string
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			a = append(a, addr.IP.String())
+		} else {
+			aaaa = append(aaaa, addr.IP.String())
+		}
+	}
+	sort.Strings(a)
+	sort.Strings(aaaa)
+	return a, aaaa
+}
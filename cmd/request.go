@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// headers of a raw request that are left to the client, as they describe the connection rather than the request
+var rawRequestSkipHeaders = map[string]bool{
+	"Accept-Encoding": true, "Connection": true, "Content-Length": true, "Host": true, "Keep-Alive": true,
+	"Proxy-Connection": true, "Transfer-Encoding": true, "Upgrade": true,
+}
+
+// rawRequest is a request read from a file, E.g. saved from Burp
+type rawRequest struct {
+	// URL of the request. It has no scheme unless the request line has an absolute URL, as raw requests don't say
+	// whether they were sent over TLS.
+	URL     string
+	Method  string
+	Body    []byte
+	Headers map[string]string
+}
+
+// readRequestFile parses the raw HTTP request in filename
+func readRequestFile(filename string) (*rawRequest, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	req, err := http.ReadRequest(bufio.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := &rawRequest{
+		URL:     req.Host + req.URL.RequestURI(),
+		Method:  req.Method,
+		Body:    body,
+		Headers: make(map[string]string),
+	}
+	if req.URL.IsAbs() {
+		raw.URL = req.URL.String()
+	}
+	for name, values := range req.Header {
+		if rawRequestSkipHeaders[name] {
+			continue
+		}
+		separator := ", "
+		if name == "Cookie" {
+			separator = "; "
+		}
+		raw.Headers[name] = strings.Join(values, separator)
+	}
+	return raw, nil
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/palaziv/hakrawler/crawler"
+)
+
+// dashboardHTML is the web UI served at /, browsing the jobs and their results through the API
+//
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+// jobRequest is the body accepted by POST /jobs. Omitted options take the same defaults as the command-line flags.
+type jobRequest struct {
+	URLs             []string          `json:"urls"`
+	Depth            int               `json:"depth"`
+	Threads          int               `json:"threads"`
+	Size             int               `json:"size"`
+	Timeout          int               `json:"timeout"`
+	Inside           bool              `json:"inside"`
+	Subs             bool              `json:"subs"`
+	Insecure         bool              `json:"insecure"`
+	DisableRedirects bool              `json:"disable_redirects"`
+	Headers          map[string]string `json:"headers"`
+	Proxy            string            `json:"proxy"`
+	NoVisit          []string          `json:"no_visit"` // crawler.DefaultNoVisit when omitted, [] to visit everything
+}
+
+// job is a crawl submitted through the API
+type job struct {
+	ID       string     `json:"id"`
+	URLs     []string   `json:"urls"`
+	Status   string     `json:"status"`
+	Found    int        `json:"found"`
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	results []crawler.Result
+}
+
+// server keeps track of the jobs submitted, until they have been finished for jobTTL
+type server struct {
+	mu     sync.Mutex
+	nextID int
+	jobs   map[string]*job
+	token  string        // required as a bearer token on every API request, none when empty
+	jobTTL time.Duration // how long finished jobs and their results are kept, forever when 0
+}
+
+// serve runs hakrawler as an HTTP API server
+func serve(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:8080", "Address to listen on. Anyone who can reach it can crawl any URL through the server, so set -token before listening on other interfaces, E.g. -addr :8080")
+	grpcAddr := fs.String("grpc-addr", "", "Address to also serve the gRPC API on, with the same jobs as the HTTP API. E.g. -grpc-addr 127.0.0.1:9090")
+	token := fs.String("token", "", "Bearer token required in the Authorization header of every API request, E.g. -token $(openssl rand -hex 16)")
+	jobTTL := fs.Duration("job-ttl", time.Hour, "Time finished jobs and their results are kept before they are deleted. 0 to keep them until the server stops.")
+	fs.Parse(args)
+
+	srv := &server{jobs: make(map[string]*job), token: *token, jobTTL: *jobTTL}
+	if *grpcAddr != "" {
+		lis, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error listening for gRPC:", err)
+			os.Exit(1)
+		}
+		log.Println("Serving gRPC on", *grpcAddr)
+		go func() {
+			log.Fatal(newGRPCServer(srv).Serve(lis))
+		}()
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jobs", srv.authorized(srv.handleJobs))
+	mux.HandleFunc("/jobs/", srv.authorized(srv.handleJob))
+	mux.HandleFunc("/", handleDashboard)
+
+	log.Println("Listening on", *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+// authorized wraps an API handler, rejecting the requests without the server's token
+func (s *server) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.validToken(r.Header.Get("Authorization")) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// validToken reports whether the value of an Authorization header holds the server's token
+func (s *server) validToken(authorization string) bool {
+	if s.token == "" {
+		return true
+	}
+	const prefix = "Bearer "
+	if len(authorization) < len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(authorization[len(prefix):]), []byte(s.token)) == 1
+}
+
+// handleDashboard serves the web UI
+func handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardHTML)
+}
+
+// handleJobs lists jobs (GET) or submits a new one (POST)
+func (s *server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		jobs := make([]*job, 0, len(s.jobs))
+		for i := 1; i <= s.nextID; i++ {
+			if j, ok := s.jobs[strconv.Itoa(i)]; ok {
+				jobs = append(jobs, j)
+			}
+		}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, snapshotJobs(jobs))
+	case http.MethodPost:
+		req := defaultJobRequest()
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+			return
+		}
+		j, err := s.submit(req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, j.snapshot())
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// defaultJobRequest returns a jobRequest with the defaults of the command-line flags
+func defaultJobRequest() jobRequest {
+	return jobRequest{Depth: 2, Threads: 8, Size: -1, Timeout: -1}
+}
+
+// submit starts the job of a request, once it is checked
+func (s *server) submit(req jobRequest) (*job, error) {
+	if len(req.URLs) == 0 {
+		return nil, errors.New("no urls supplied")
+	}
+	config := crawler.Config{
+		Headers:          req.Headers,
+		Inside:           req.Inside,
+		MaxDepth:         req.Depth,
+		MaxSize:          req.Size,
+		SubsInScope:      req.Subs,
+		DisableRedirects: req.DisableRedirects,
+		Threads:          req.Threads,
+		Insecure:         req.Insecure,
+		Timeout:          req.Timeout,
+		NoVisit:          req.NoVisit,
+	}
+	if req.Proxy != "" {
+		proxyURL, err := url.Parse(req.Proxy)
+		if err != nil {
+			return nil, errors.New("invalid proxy: " + err.Error())
+		}
+		config.Proxy = proxyURL
+	}
+	return s.start(req.URLs, config), nil
+}
+
+// job returns the job with an id, false if there is none
+func (s *server) job(id string) (*job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	return j, ok
+}
+
+// handleJob serves /jobs/{id} (status) and /jobs/{id}/results (result stream)
+func (s *server) handleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/")
+	j, ok := s.job(parts[0])
+	if !ok || len(parts) > 2 || (len(parts) == 2 && parts[1] != "results") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	if len(parts) == 1 {
+		writeJSON(w, http.StatusOK, j.snapshot())
+		return
+	}
+	j.stream(w, r)
+}
+
+// start registers a job and crawls its URLs in the background
+func (s *server) start(urls []string, config crawler.Config) *job {
+	s.mu.Lock()
+	s.nextID++
+	j := &job{
+		ID:      strconv.Itoa(s.nextID),
+		URLs:    urls,
+		Status:  "running",
+		Started: time.Now(),
+	}
+	j.cond = sync.NewCond(&j.mu)
+	s.jobs[j.ID] = j
+	s.mu.Unlock()
+
+	results := make(chan crawler.Result, config.Threads)
+	go func() {
+		for _, u := range urls {
+			target, err := targetConfig(config, u)
+			if err != nil {
+				log.Println("Error parsing URL:", err)
+				continue
+			}
+			crawler.Crawl(u, target, results)
+		}
+		close(results)
+	}()
+	go func() {
+		for res := range results {
+			j.mu.Lock()
+			j.results = append(j.results, res)
+			j.mu.Unlock()
+			j.cond.Broadcast()
+		}
+		j.mu.Lock()
+		finished := time.Now()
+		j.Status = "finished"
+		j.Finished = &finished
+		j.mu.Unlock()
+		j.cond.Broadcast()
+
+		// streams already following the job still get all its results, as they hold on to it
+		if s.jobTTL > 0 {
+			time.AfterFunc(s.jobTTL, func() {
+				s.mu.Lock()
+				delete(s.jobs, j.ID)
+				s.mu.Unlock()
+			})
+		}
+	}()
+	return j
+}
+
+// snapshot returns a copy of the job's status that is safe to serialise
+func (j *job) snapshot() *job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return &job{
+		ID:       j.ID,
+		URLs:     j.URLs,
+		Status:   j.Status,
+		Found:    len(j.results),
+		Started:  j.Started,
+		Finished: j.Finished,
+	}
+}
+
+// stream writes the job's results as newline delimited JSON, following new results until the job finishes
+func (j *job) stream(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	flush := func() {}
+	if flusher != nil {
+		flush = flusher.Flush
+	}
+	j.follow(r.Context(), func(res crawler.Result) error { return enc.Encode(res) }, flush)
+}
+
+// follow passes the job's results to send, following new results until the job finishes, send fails or ctx is done.
+// idle is called whenever all results so far have been sent, before waiting for more.
+func (j *job) follow(ctx context.Context, send func(crawler.Result) error, idle func()) {
+	go func() {
+		<-ctx.Done()
+		j.mu.Lock()
+		j.cond.Broadcast()
+		j.mu.Unlock()
+	}()
+
+	sent := 0
+	j.mu.Lock()
+	for ctx.Err() == nil {
+		for sent < len(j.results) {
+			res := j.results[sent]
+			sent++
+			j.mu.Unlock()
+			if err := send(res); err != nil {
+				return
+			}
+			j.mu.Lock()
+		}
+		if j.Status != "running" {
+			break
+		}
+		j.mu.Unlock()
+		idle()
+		j.mu.Lock()
+		// results may have arrived while idle
+		if sent < len(j.results) || ctx.Err() != nil {
+			continue
+		}
+		j.cond.Wait()
+	}
+	j.mu.Unlock()
+}
+
+func snapshotJobs(jobs []*job) []*job {
+	snapshots := make([]*job, len(jobs))
+	for i, j := range jobs {
+		snapshots[i] = j.snapshot()
+	}
+	return snapshots
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/palaziv/hakrawler/crawler"
+)
+
+// handlePauseSignals pauses the crawl on SIGUSR1 and resumes it on SIGUSR2
+func handlePauseSignals(p *crawler.Pauser) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for sig := range sigs {
+			if sig == syscall.SIGUSR1 {
+				log.Println("[paused] waiting for in-flight requests, send SIGUSR2 to resume")
+				p.Pause()
+			} else {
+				log.Println("[resumed]")
+				p.Resume()
+			}
+		}
+	}()
+}
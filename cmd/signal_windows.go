@@ -0,0 +1,9 @@
+//go:build windows
+// +build windows
+
+package main
+
+import "github.com/palaziv/hakrawler/crawler"
+
+// handlePauseSignals is a no-op, as Windows has no SIGUSR1/SIGUSR2
+func handlePauseSignals(p *crawler.Pauser) {}
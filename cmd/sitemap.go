@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/palaziv/hakrawler/crawler"
+)
+
+// most urls a sitemap may list, and longest url, according to sitemaps.org
+const (
+	sitemapMaxURLs   = 50000
+	sitemapMaxLength = 2048
+)
+
+// sitemap collects the in scope pages visited, by host: the in scope links that aren't scripts, styles, media, forms
+// submitted with POST or left unvisited by -no-visit, for -export-sitemap
+type sitemap struct {
+	noVisit *regexp.Regexp // nil when every link is visited
+	seen    map[string]bool
+	hosts   map[string][]string // scheme://host -> its pages
+}
+
+func newSitemap(noVisit []string) *sitemap {
+	s := &sitemap{seen: make(map[string]bool), hosts: make(map[string][]string)}
+	if len(noVisit) > 0 {
+		s.noVisit = crawler.NoVisitFilter(noVisit)
+	}
+	return s
+}
+
+// add records the url of a result, if it is a page that was visited
+func (s *sitemap) add(res crawler.Result) {
+	if res.Scope != "in" || crawler.IsFindingSource(res.Source) || res.Method != "" || isJavaScript(res) || staticExtensions[extension(res.URL)] {
+		return
+	}
+	page := res.URL
+	if i := strings.Index(page, "#"); i != -1 {
+		page = page[:i]
+	}
+	if len(page) > sitemapMaxLength || s.seen[page] || (s.noVisit != nil && s.noVisit.MatchString(page)) {
+		return
+	}
+	u, err := url.Parse(page)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return
+	}
+	s.seen[page] = true
+	origin := u.Scheme + "://" + strings.ToLower(u.Host)
+	s.hosts[origin] = append(s.hosts[origin], page)
+}
+
+// write saves the pages to filename as a sitemap. A sitemap may only list the urls of one host, so with several hosts
+// each gets its own file named after it, E.g. sitemap-www.example.com.xml. Beyond 50000 pages, the others go to files
+// numbered from 2, E.g. sitemap-2.xml.
+func (s *sitemap) write(filename string) error {
+	if len(s.hosts) == 0 {
+		return writeSitemap(filename, nil)
+	}
+	origins := make([]string, 0, len(s.hosts))
+	for origin := range s.hosts {
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+	ext := filepath.Ext(filename)
+	for _, origin := range origins {
+		base := filename
+		if len(origins) > 1 {
+			// http hosts are told apart from the https ones, and ports from the extension, E.g. sitemap-http-localhost_8080.xml
+			name := strings.TrimPrefix(origin, "https://")
+			if strings.HasPrefix(origin, "http://") {
+				name = "http-" + strings.TrimPrefix(origin, "http://")
+			}
+			base = strings.TrimSuffix(filename, ext) + "-" + strings.Replace(name, ":", "_", 1) + ext
+		}
+		if err := writeSitemaps(base, s.hosts[origin]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSitemaps saves pages to filename, and to files numbered from 2 beyond 50000 pages
+func writeSitemaps(filename string, pages []string) error {
+	ext := filepath.Ext(filename)
+	for i := 0; i*sitemapMaxURLs < len(pages); i++ {
+		name := filename
+		if i > 0 {
+			name = strings.TrimSuffix(filename, ext) + "-" + strconv.Itoa(i+1) + ext
+		}
+		end := (i + 1) * sitemapMaxURLs
+		if end > len(pages) {
+			end = len(pages)
+		}
+		if err := writeSitemap(name, pages[i*sitemapMaxURLs:end]); err != nil {
+			return err
+		}
+	}
+	if len(pages) > sitemapMaxURLs {
+		log.Println("[sitemap] more than " + strconv.Itoa(sitemapMaxURLs) + " pages, split over " +
+			strconv.Itoa((len(pages)+sitemapMaxURLs-1)/sitemapMaxURLs) + " files")
+	}
+	return nil
+}
+
+func writeSitemap(filename string, pages []string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	w.WriteString(xml.Header)
+	w.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` + "\n")
+	for _, page := range pages {
+		w.WriteString("  <url><loc>")
+		xml.EscapeText(w, []byte(page))
+		w.WriteString("</loc></url>\n")
+	}
+	w.WriteString("</urlset>\n")
+	return w.Flush()
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/palaziv/hakrawler/crawler"
+)
+
+// sortedCrawl runs crawl, holding back what it finds until it is done, then sends it to results sorted by host,
+// then path, so the output of runs can be diffed
+func sortedCrawl(crawl func(results chan<- crawler.Result), results chan<- crawler.Result) {
+	found := make(chan crawler.Result)
+	done := make(chan struct{})
+	var batch []crawler.Result
+	go func() {
+		for res := range found {
+			batch = append(batch, res)
+		}
+		close(done)
+	}()
+	crawl(found)
+	close(found)
+	<-done
+
+	sort.SliceStable(batch, func(i, j int) bool {
+		hostI, pathI := sortKey(batch[i].URL)
+		hostJ, pathJ := sortKey(batch[j].URL)
+		if hostI != hostJ {
+			return hostI < hostJ
+		}
+		if pathI != pathJ {
+			return pathI < pathJ
+		}
+		if batch[i].URL != batch[j].URL {
+			return batch[i].URL < batch[j].URL
+		}
+		if batch[i].Source != batch[j].Source {
+			return batch[i].Source < batch[j].Source
+		}
+		return batch[i].Where < batch[j].Where
+	})
+	for _, res := range batch {
+		results <- res
+	}
+}
+
+// sortKey returns the host and the path and query of a url, or the url itself as the host if it has none, E.g. for
+// subdomains
+func sortKey(raw string) (string, string) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return strings.ToLower(raw), ""
+	}
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return strings.ToLower(u.Host), path
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/palaziv/hakrawler/crawler"
+)
+
+// seedStats holds the counts for one url from stdin
+type seedStats struct {
+	Seed     string `json:"seed"`
+	URLs     int    `json:"urls"`
+	Requests int    `json:"requests"`
+	Errors   int    `json:"errors"`
+}
+
+// runStats collects the summary printed at the end of the run, for -stats
+type runStats struct {
+	mu      sync.Mutex
+	start   time.Time
+	sources map[string]int
+	seeds   []seedStats
+}
+
+func newRunStats() *runStats {
+	return &runStats{start: time.Now(), sources: make(map[string]int)}
+}
+
+// found counts a result by its source
+func (rs *runStats) found(res crawler.Result) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.sources[res.Source]++
+}
+
+// crawl runs crawler.Crawl for a seed, forwarding its results and recording its counts
+func (rs *runStats) crawl(url string, config crawler.Config, results chan<- crawler.Result) {
+	config.Stats = crawler.NewStats()
+	seedResults := make(chan crawler.Result)
+	done := make(chan struct{})
+	urls := 0
+	go func() {
+		for res := range seedResults {
+			if res.Source != "error" {
+				urls++
+			}
+			results <- res
+		}
+		close(done)
+	}()
+	crawler.Crawl(url, config, seedResults)
+	close(seedResults)
+	<-done
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.seeds = append(rs.seeds, seedStats{
+		Seed:     url,
+		URLs:     urls,
+		Requests: config.Stats.Requests(),
+		Errors:   config.Stats.Errors(),
+	})
+}
+
+// print writes the summary as a JSON line, or as a few tagged lines
+func (rs *runStats) print(w io.Writer, showJson bool) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	requests, errors := 0, 0
+	for _, seed := range rs.seeds {
+		requests += seed.Requests
+		errors += seed.Errors
+	}
+	duration := time.Since(rs.start)
+
+	if showJson {
+		bytes, _ := json.Marshal(struct {
+			Type     string         `json:"type"`
+			Duration float64        `json:"duration"`
+			Requests int            `json:"requests"`
+			Errors   int            `json:"errors"`
+			Sources  map[string]int `json:"sources"`
+			Seeds    []seedStats    `json:"seeds"`
+		}{"stats", duration.Seconds(), requests, errors, rs.sources, rs.seeds})
+		fmt.Fprintln(w, string(bytes))
+		return
+	}
+
+	fmt.Fprintf(w, "[stats] %d seeds, %d requests, %d errors in %s\n", len(rs.seeds), requests, errors, duration.Round(time.Millisecond))
+	sources := make([]string, 0, len(rs.sources))
+	for source := range rs.sources {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	counts := make([]string, 0, len(sources))
+	for _, source := range sources {
+		counts = append(counts, fmt.Sprintf("%s %d", source, rs.sources[source]))
+	}
+	if len(counts) > 0 {
+		fmt.Fprintln(w, "[stats] found: "+strings.Join(counts, ", "))
+	}
+	for _, seed := range rs.seeds {
+		fmt.Fprintf(w, "[stats] [%s] %d urls, %d requests, %d errors\n", seed.Seed, seed.URLs, seed.Requests, seed.Errors)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/palaziv/hakrawler/crawler"
+)
+
+// targetLine is a line of stdin given as JSON, so each target can be crawled with its own headers and settings, E.g.
+// {"url": "https://example.com", "headers": {"Cookie": "session=abc"}, "depth": 3}. Settings left out are those of
+// the flags, and headers are added to those of -h and -H.
+type targetLine struct {
+	URL      string            `json:"url"`
+	Headers  map[string]string `json:"headers"`
+	Depth    *int              `json:"depth"`
+	Threads  *int              `json:"threads"`
+	Size     *int              `json:"size"`
+	Timeout  *int              `json:"timeout"`
+	Inside   *bool             `json:"inside"`
+	Subs     *bool             `json:"subs"`
+	Insecure *bool             `json:"insecure"`
+}
+
+// isTargetLine reports whether a line of stdin is a JSON target rather than a url
+func isTargetLine(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "{")
+}
+
+// parseTargetLine returns the url of a JSON line of stdin, and a copy of base with its settings applied
+func parseTargetLine(line string, base crawler.Config) (string, crawler.Config, error) {
+	var target targetLine
+	if err := json.Unmarshal([]byte(line), &target); err != nil {
+		return "", base, err
+	}
+	if target.URL == "" {
+		return "", base, errors.New("no url in " + line)
+	}
+
+	if len(target.Headers) > 0 {
+		merged := make(map[string]string, len(base.Headers)+len(target.Headers))
+		for header, value := range base.Headers {
+			merged[header] = value
+		}
+		for header, value := range target.Headers {
+			merged[header] = value
+		}
+		base.Headers = merged
+	}
+	if target.Depth != nil {
+		base.MaxDepth = *target.Depth
+	}
+	if target.Threads != nil {
+		base.Threads = *target.Threads
+	}
+	if target.Size != nil {
+		base.MaxSize = *target.Size
+	}
+	if target.Timeout != nil {
+		base.Timeout = *target.Timeout
+	}
+	if target.Inside != nil {
+		base.Inside = *target.Inside
+	}
+	if target.Subs != nil {
+		base.SubsInScope = *target.Subs
+	}
+	if target.Insecure != nil {
+		base.Insecure = *target.Insecure
+	}
+	return target.URL, base, nil
+}
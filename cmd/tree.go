@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/palaziv/hakrawler/crawler"
+)
+
+// treeNode is a path segment of a pathTree, with the segments found below it
+type treeNode struct {
+	children map[string]*treeNode
+	dir      bool // a url ended in a slash after it
+}
+
+func newTreeNode() *treeNode {
+	return &treeNode{children: make(map[string]*treeNode)}
+}
+
+// pathTree collects the paths of the urls found on each host, for -tree
+type pathTree struct {
+	hosts map[string]*treeNode // scheme://host -> its root
+}
+
+func newPathTree() *pathTree {
+	return &pathTree{hosts: make(map[string]*treeNode)}
+}
+
+// add records the path of the url of a result, without its query and fragment
+func (t *pathTree) add(res crawler.Result) {
+	if crawler.IsFindingSource(res.Source) {
+		return
+	}
+	u, err := url.Parse(res.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return
+	}
+	origin := u.Scheme + "://" + strings.ToLower(u.Host)
+	node, ok := t.hosts[origin]
+	if !ok {
+		node = newTreeNode()
+		t.hosts[origin] = node
+	}
+	segments := strings.Split(strings.TrimPrefix(u.EscapedPath(), "/"), "/")
+	for i, segment := range segments {
+		// a trailing slash marks the directory itself, E.g. /admin/ adds admin/ but no empty file below it
+		if segment == "" && i == len(segments)-1 {
+			node.dir = true
+			break
+		}
+		child, ok := node.children[segment]
+		if !ok {
+			child = newTreeNode()
+			node.children[segment] = child
+		}
+		node = child
+	}
+}
+
+// print writes the tree of each host, directories ending in a slash, E.g.
+//
+//	https://example.com
+//	  admin/
+//	    login.php
+//	  index.html
+func (t *pathTree) print(w io.Writer) {
+	origins := make([]string, 0, len(t.hosts))
+	for origin := range t.hosts {
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+	for _, origin := range origins {
+		printLine(w, origin)
+		t.hosts[origin].print(w, "  ")
+	}
+}
+
+func (n *treeNode) print(w io.Writer, indent string) {
+	segments := make([]string, 0, len(n.children))
+	for segment := range n.children {
+		segments = append(segments, segment)
+	}
+	sort.Strings(segments)
+	for _, segment := range segments {
+		child := n.children[segment]
+		if child.dir || len(child.children) > 0 {
+			printLine(w, indent+segment+"/")
+			child.print(w, indent+"  ")
+		} else {
+			printLine(w, indent+segment)
+		}
+	}
+}
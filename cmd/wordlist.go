@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// wordlist collects the path segments, file names and parameter names of the urls found
+type wordlist struct {
+	words map[string]bool
+}
+
+func newWordlist() *wordlist {
+	return &wordlist{words: make(map[string]bool)}
+}
+
+// add records the words in rawURL
+func (wl *wordlist) add(rawURL string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return
+	}
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment = strings.TrimSpace(segment); segment != "" {
+			wl.words[segment] = true
+		}
+	}
+	for name := range u.Query() {
+		if name = strings.TrimSpace(name); name != "" {
+			wl.words[name] = true
+		}
+	}
+}
+
+// write saves the words to filename, one per line in sorted order
+func (wl *wordlist) write(filename string) error {
+	words := make([]string, 0, len(wl.words))
+	for word := range wl.words {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, word := range words {
+		w.WriteString(word + "\n")
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
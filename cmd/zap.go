@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/url"
+	"os"
+	"regexp"
+
+	"github.com/palaziv/hakrawler/crawler"
+)
+
+// how ZAP splits the parameters of urls and form bodies
+type zapParser struct {
+	Class  string `xml:"class"`
+	Config string `xml:"config"`
+}
+
+var zapStandardParser = zapParser{
+	Class:  "org.zaproxy.zap.model.StandardParameterParser",
+	Config: `{"kvps":"&","kvs":"=","struct":[]}`,
+}
+
+// zapContext collects the in scope origins found, written as a ZAP context including them, for -zap-context
+type zapContext struct {
+	origins map[string]bool
+}
+
+func newZAPContext() *zapContext {
+	return &zapContext{origins: make(map[string]bool)}
+}
+
+// add records the origin of a result if it is in scope
+func (z *zapContext) add(res crawler.Result) {
+	if res.Scope != "in" || crawler.IsFindingSource(res.Source) {
+		return
+	}
+	u, err := url.Parse(res.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return
+	}
+	z.origins[u.Scheme+"://"+u.Host] = true
+}
+
+// write saves the context to filename, for Import Context in ZAP
+func (z *zapContext) write(filename string) error {
+	var regexes []string
+	for _, origin := range sorted(z.origins) {
+		regexes = append(regexes, regexp.QuoteMeta(origin)+"(/.*)?")
+	}
+	type context struct {
+		Name       string    `xml:"name"`
+		Desc       string    `xml:"desc"`
+		InScope    bool      `xml:"inscope"`
+		IncRegexes []string  `xml:"incregexes"`
+		URLParser  zapParser `xml:"urlparser"`
+		PostParser zapParser `xml:"postparser"`
+	}
+	bytes, err := xml.MarshalIndent(struct {
+		XMLName xml.Name `xml:"configuration"`
+		Context context  `xml:"context"`
+	}{Context: context{
+		Name:       "hakrawler",
+		Desc:       "Hosts crawled by hakrawler",
+		InScope:    true,
+		IncRegexes: regexes,
+		URLParser:  zapStandardParser,
+		PostParser: zapStandardParser,
+	}}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append([]byte(xml.Header), append(bytes, '\n')...), 0644)
+}
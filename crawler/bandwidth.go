@@ -0,0 +1,72 @@
+package crawler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// the most read from a response body at a time with a bandwidth limit, so reads are spread out smoothly
+const bandwidthChunk = 16 * 1024
+
+// BandwidthLimit caps the rate response bodies are read at, across every crawl sharing it
+type BandwidthLimit struct {
+	mu   sync.Mutex
+	rate int64     // bytes per second
+	next time.Time // when the bytes read so far are paid for
+}
+
+// NewBandwidthLimit returns a BandwidthLimit of rate bytes per second
+func NewBandwidthLimit(rate int64) *BandwidthLimit {
+	return &BandwidthLimit{rate: rate}
+}
+
+// wait blocks until n more bytes may be read, or until ctx is done
+func (b *BandwidthLimit) wait(ctx context.Context, n int) {
+	b.mu.Lock()
+	now := time.Now()
+	if b.next.Before(now) {
+		b.next = now
+	}
+	b.next = b.next.Add(time.Duration(int64(n) * int64(time.Second) / b.rate))
+	delay := b.next.Sub(now)
+	b.mu.Unlock()
+
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+	}
+}
+
+// bandwidthTransport reads the bodies of responses within a BandwidthLimit
+type bandwidthTransport struct {
+	next  http.RoundTripper
+	limit *BandwidthLimit
+}
+
+func (t *bandwidthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err == nil {
+		resp.Body = &limitedBody{ReadCloser: resp.Body, ctx: req.Context(), limit: t.limit}
+	}
+	return resp, err
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	ctx   context.Context
+	limit *BandwidthLimit
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if len(p) > bandwidthChunk {
+		p = p[:bandwidthChunk]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.limit.wait(b.ctx, n)
+	}
+	return n, err
+}
@@ -0,0 +1,93 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CircuitBreaker skips the hosts that keep failing, E.g. because they are down or drop our connections, so requests
+// to them don't each wait for the timeout
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  map[string]int       // host -> consecutive requests that got no response
+	openUntil map[string]time.Time // host -> when requests to it are sent again
+}
+
+// NewCircuitBreaker returns a CircuitBreaker that skips a host for cooldown after threshold consecutive failures
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		failures:  make(map[string]int),
+		openUntil: make(map[string]time.Time),
+	}
+}
+
+// errHostSkipped is the error of the requests to hosts skipped by a CircuitBreaker
+var errHostSkipped = errors.New("host skipped after failing repeatedly")
+
+// allow reports whether a request may be sent to host
+func (b *CircuitBreaker) allow(host string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	until, ok := b.openUntil[host]
+	if !ok {
+		return true
+	}
+	if time.Now().Before(until) {
+		return false
+	}
+	// give the host another chance, a single failure skips it again
+	delete(b.openUntil, host)
+	b.failures[host] = b.threshold - 1
+	return true
+}
+
+// failed records a request to host that got no response
+func (b *CircuitBreaker) failed(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.openUntil[host]; ok {
+		return
+	}
+	b.failures[host]++
+	if b.failures[host] >= b.threshold {
+		b.openUntil[host] = time.Now().Add(b.cooldown)
+		log.Println("[breaker] " + host + " failed " + strconv.Itoa(b.failures[host]) + " times in a row, skipping it for " + b.cooldown.String())
+	}
+}
+
+// succeeded records a response from host
+func (b *CircuitBreaker) succeeded(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.failures, host)
+}
+
+// breakerTransport fails the requests to the hosts skipped by a CircuitBreaker without sending them, including those
+// that were already queued when the host was skipped
+type breakerTransport struct {
+	next    http.RoundTripper
+	breaker *CircuitBreaker
+	ctx     context.Context // the context of the crawl, failures once it is done don't count
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.breaker.allow(req.URL.Host) {
+		return nil, errHostSkipped
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err == nil {
+		t.breaker.succeeded(req.URL.Host)
+	} else if t.ctx.Err() == nil && !errors.Is(err, errBudgetSpent) {
+		t.breaker.failed(req.URL.Host)
+	}
+	return resp, err
+}
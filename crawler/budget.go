@@ -0,0 +1,71 @@
+package crawler
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// errBudgetSpent is the error of the requests sent once a Budget is spent
+var errBudgetSpent = errors.New("request budget spent")
+
+// Budget limits the number of requests a crawl may send
+type Budget struct {
+	mu    sync.Mutex
+	limit int
+	used  int
+}
+
+// NewBudget returns a Budget allowing limit requests
+func NewBudget(limit int) *Budget {
+	return &Budget{limit: limit}
+}
+
+// Take reserves a request, returning false once the budget is spent
+func (b *Budget) Take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.used >= b.limit {
+		return false
+	}
+	b.used++
+	return true
+}
+
+// Spent reports whether no requests are left
+func (b *Budget) Spent() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used >= b.limit
+}
+
+// Used returns the number of requests taken so far
+func (b *Budget) Used() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used
+}
+
+// Transport returns a RoundTripper taking a request from the budget for every request sent through next, and
+// failing those sent once it is spent. It returns next for a nil Budget.
+func (b *Budget) Transport(next http.RoundTripper) http.RoundTripper {
+	if b == nil {
+		return next
+	}
+	return &budgetTransport{next: next, budget: b}
+}
+
+type budgetTransport struct {
+	next   http.RoundTripper
+	budget *Budget
+}
+
+func (t *budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.budget.Take() {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, errBudgetSpent
+	}
+	return t.next.RoundTrip(req)
+}
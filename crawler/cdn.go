@@ -0,0 +1,97 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errCDN is the error of visits to hosts on CDNs with SkipCDN
+var errCDN = errors.New("host is on a CDN")
+
+// domains of CDNs, matched against hosts and the names they are an alias of
+var cdnDomains = map[string]string{
+	"cloudfront.net": "cloudfront", "akamaihd.net": "akamai", "akamaized.net": "akamai", "akamaiedge.net": "akamai",
+	"akamaitechnologies.com": "akamai", "edgekey.net": "akamai", "edgesuite.net": "akamai", "fastly.net": "fastly",
+	"fastlylb.net": "fastly", "azureedge.net": "azure", "azurefd.net": "azure", "cdn.cloudflare.net": "cloudflare",
+	"cdnjs.cloudflare.com": "cloudflare", "jsdelivr.net": "jsdelivr", "unpkg.com": "unpkg", "gstatic.com": "google",
+	"googleusercontent.com": "google", "bootstrapcdn.com": "stackpath", "stackpathdns.com": "stackpath",
+	"b-cdn.net": "bunny", "cdn77.org": "cdn77", "kxcdn.com": "keycdn", "llnwd.net": "limelight",
+	"edgecastcdn.net": "edgecast", "incapdns.net": "imperva", "cdngc.net": "cdnetworks",
+}
+
+// cdnProvider returns the CDN the domain name belongs to, empty if it isn't a known one
+func cdnProvider(name string) string {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	for {
+		if provider, ok := cdnDomains[name]; ok {
+			return provider
+		}
+		i := strings.Index(name, ".")
+		if i == -1 {
+			return ""
+		}
+		name = name[i+1:]
+	}
+}
+
+// cdnDetector tells the hosts served by CDNs apart, by their names or the names they are an alias of
+type cdnDetector struct {
+	mu        sync.Mutex
+	providers map[string]string // host -> CDN, empty if it isn't on one
+}
+
+func newCDNDetector() *cdnDetector {
+	return &cdnDetector{providers: make(map[string]string)}
+}
+
+// provider returns the CDN host is served by, empty if it isn't a known one, and whether host is seen for the first time
+func (d *cdnDetector) provider(host string) (string, bool) {
+	host = strings.ToLower(host)
+	d.mu.Lock()
+	provider, ok := d.providers[host]
+	d.mu.Unlock()
+	if ok {
+		return provider, false
+	}
+
+	provider = cdnProvider(host)
+	if provider == "" && net.ParseIP(host) == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if cname, err := net.DefaultResolver.LookupCNAME(ctx, host); err == nil {
+			provider = cdnProvider(cname)
+		}
+		cancel()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.providers[host]; ok {
+		return d.providers[host], false
+	}
+	d.providers[host] = provider
+	return provider, true
+}
+
+// onCDN reports whether link is on a CDN host other than the one crawled, sending a Result with Source "cdn" the
+// first time the host is seen
+func (o *output) onCDN(where string, link string) bool {
+	if o.cdns == nil {
+		return false
+	}
+	u, err := url.Parse(link)
+	if err != nil || strings.EqualFold(u.Hostname(), o.config.Hostname) || !o.config.inScopeHost(u.Hostname()) {
+		return false
+	}
+	provider, first := o.cdns.provider(u.Hostname())
+	if provider != "" && first {
+		log.Println("[cdn] " + u.Hostname() + " is on " + provider + ", not crawling it")
+		o.emit(Result{Source: "cdn", URL: u.Scheme + "://" + u.Host, Where: where, Error: provider})
+	}
+	return provider != ""
+}
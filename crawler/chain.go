@@ -0,0 +1,40 @@
+package crawler
+
+import "sync"
+
+// parentTracker records the page each link was first found on, so the chain of pages followed from the seed to any
+// page can be told
+type parentTracker struct {
+	mu      sync.Mutex
+	parents map[string]string // canonical link -> page it was first found on
+}
+
+func newParentTracker() *parentTracker {
+	return &parentTracker{parents: make(map[string]string)}
+}
+
+// add records that link was found on page, unless it was found before
+func (p *parentTracker) add(link string, page string) {
+	key := canonicalURL(link)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.parents[key]; !ok && key != canonicalURL(page) {
+		p.parents[key] = page
+	}
+}
+
+// chain returns the pages followed from the seed to page, both included
+func (p *parentTracker) chain(page string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	chain := []string{page}
+	seen := map[string]bool{canonicalURL(page): true}
+	for {
+		parent, ok := p.parents[canonicalURL(chain[0])]
+		if !ok || seen[canonicalURL(parent)] {
+			return chain
+		}
+		seen[canonicalURL(parent)] = true
+		chain = append([]string{parent}, chain...)
+	}
+}
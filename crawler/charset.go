@@ -0,0 +1,24 @@
+package crawler
+
+import (
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+	"golang.org/x/net/html/charset"
+)
+
+// toUTF8 converts HTML pages that declare their encoding in a <meta> tag or with a byte order mark, rather than in
+// their Content-Type header, to UTF-8 so they are parsed correctly. colly converts those declaring it in the header.
+func toUTF8(r *colly.Response) {
+	contentType := strings.ToLower(r.Headers.Get("Content-Type"))
+	if len(r.Body) == 0 || !strings.Contains(contentType, "html") || strings.Contains(contentType, "charset") {
+		return
+	}
+	encoding, name, _ := charset.DetermineEncoding(r.Body, contentType)
+	if name == "utf-8" {
+		return
+	}
+	if body, err := encoding.NewDecoder().Bytes(r.Body); err == nil {
+		r.Body = body
+	}
+}
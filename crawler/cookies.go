@@ -0,0 +1,85 @@
+package crawler
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// CookieEntry records a cookie set by a host, with the attributes relevant to session handling
+type CookieEntry struct {
+	Host     string
+	Name     string
+	URL      string // where the cookie was first set
+	Path     string `json:",omitempty"`
+	Domain   string `json:",omitempty"`
+	Secure   bool
+	HttpOnly bool
+	SameSite string `json:",omitempty"` // Lax, Strict or None, empty if not specified
+}
+
+// CookieReport collects the cookies set by every host crawled, from Set-Cookie headers
+type CookieReport struct {
+	mu      sync.Mutex
+	entries map[string]CookieEntry
+}
+
+// NewCookieReport returns an empty report
+func NewCookieReport() *CookieReport {
+	return &CookieReport{entries: make(map[string]CookieEntry)}
+}
+
+func (r *CookieReport) add(url string, host string, headers http.Header) {
+	cookies := (&http.Response{Header: headers}).Cookies()
+	if len(cookies) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, cookie := range cookies {
+		key := host + " " + cookie.Name
+		if _, ok := r.entries[key]; ok {
+			continue
+		}
+		r.entries[key] = CookieEntry{
+			Host:     host,
+			Name:     cookie.Name,
+			URL:      url,
+			Path:     cookie.Path,
+			Domain:   cookie.Domain,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+			SameSite: sameSiteName(cookie.SameSite),
+		}
+	}
+}
+
+// sameSiteName returns the value of the SameSite attribute
+func sameSiteName(mode http.SameSite) string {
+	switch mode {
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteNoneMode:
+		return "None"
+	}
+	return ""
+}
+
+// Entries returns the recorded cookies sorted by host and name
+func (r *CookieReport) Entries() []CookieEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries := make([]CookieEntry, 0, len(r.entries))
+	for _, entry := range r.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Host != entries[j].Host {
+			return entries[i].Host < entries[j].Host
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CORSEntry records the CORS headers returned by an endpoint
+type CORSEntry struct {
+	URL              string
+	AllowOrigin      string
+	AllowCredentials bool
+	// Reflected is set when the Origin sent as a probe was echoed back in Access-Control-Allow-Origin
+	Reflected bool
+}
+
+// CORSReport collects the CORS configuration of every endpoint crawled that returned Access-Control-Allow-Origin
+type CORSReport struct {
+	mu      sync.Mutex
+	entries map[string]CORSEntry
+}
+
+// NewCORSReport returns an empty report
+func NewCORSReport() *CORSReport {
+	return &CORSReport{entries: make(map[string]CORSEntry)}
+}
+
+func (r *CORSReport) add(url string, origin string, headers http.Header) {
+	allowOrigin := headers.Get("Access-Control-Allow-Origin")
+	if allowOrigin == "" {
+		return
+	}
+	entry := CORSEntry{
+		URL:              url,
+		AllowOrigin:      allowOrigin,
+		AllowCredentials: strings.EqualFold(headers.Get("Access-Control-Allow-Credentials"), "true"),
+		Reflected:        origin != "" && allowOrigin == origin,
+	}
+	r.mu.Lock()
+	r.entries[url] = entry
+	r.mu.Unlock()
+}
+
+// Entries returns the recorded endpoints sorted by URL
+func (r *CORSReport) Entries() []CORSEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries := make([]CORSEntry, 0, len(r.entries))
+	for _, entry := range r.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].URL < entries[j].URL })
+	return entries
+}
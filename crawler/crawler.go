@@ -1,154 +1,917 @@
 package crawler
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
-	"encoding/json"
+	"errors"
 	"log"
+	"net"
 	"net/http"
-	"net/url"
+	neturl "net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gocolly/colly/v2"
+	"github.com/gocolly/colly/v2/storage"
 )
 
+// UserAgent is the default User-Agent header, used unless Config.Headers sets another one
+const UserAgent = "Mozilla/5.0 (X11; Linux x86_64; rv:78.0) Gecko/20100101 Firefox/78.0"
+
+// matches quoted references to JavaScript files, E.g. "/static/app.js?v=2"
+var jsFileRegex = regexp.MustCompile("[\"'`]([^\"'`\\s<>]+\\.m?js(?:\\?[^\"'`\\s<>]*)?)[\"'`]")
+
 type Result struct {
-	Source string
-	URL    string
-	Where  string
+	Source    string
+	URL       string
+	Where     string
+	Seed      string   `json:",omitempty"` // the URL crawled that led to URL
+	Depth     int      `json:",omitempty"` // the number of pages followed from Seed to find URL, 1 for links on Seed
+	Chain     []string `json:",omitempty"` // the pages followed from Seed to Where, both included
+	Redirects []string `json:",omitempty"` // for Source "redirect", the chain of URLs from Where to URL
+	FinalURL  string   `json:",omitempty"` // the URL that URL resolved to after redirects, if it was visited
+	Status    int      `json:",omitempty"` // for Source "broken", the status returned, 0 if the request failed
+	Error     string   `json:",omitempty"` // for Source "broken" and "error", why the request failed, for "trap", why URL isn't followed, for "cdn" and "waf", the CDN or WAF, for "truncated", the size limit, for "unchecked", why it wasn't requested
+	Kind      string   `json:",omitempty"` // for Source "error", what failed: dns, timeout, tls, connection or skipped
+	Scope     string   `json:",omitempty"` // "in" if the host of URL is in scope, "out" if it isn't
+	Match     string   `json:",omitempty"` // for Source "grep", the match with some context around it
+	Method    string   `json:",omitempty"` // for Source "form", the method of the form, empty for GET
+	Body      string   `json:",omitempty"` // for Source "form", the fields of the form with their default values, url encoded
+}
+
+// LimitRule limits the requests sent to the hosts matching DomainGlob, E.g. *.example.com. The host includes the
+// port if the URL specifies one.
+type LimitRule struct {
+	DomainGlob  string
+	Parallelism int           // maximum number of requests in flight, 0 for no limit
+	Delay       time.Duration // time to wait between requests
 }
 
-func Crawl(url string, headers map[string]string, allowedDomains []string, inside bool, maxDepth int, maxSize int, subsInScope bool, disableRedirects bool, threads int, proxy *url.URL, insecure bool, timeout int, hostname string, showSource bool, showWhere bool, showJson bool, results chan<- string) {
+// Config holds the settings for crawling a single URL
+type Config struct {
+	Headers          map[string]string
+	HostHeaders      []HostHeader // headers sent only to matching hosts
+	AllowedDomains   []string
+	Inside           bool
+	MaxDepth         int
+	Strategy         string      // StrategyBreadth or StrategyDepth to send requests in that order, empty to send them as found
+	Prioritize       bool        // send the requests for urls likely to be interesting first, E.g. with parameters or under /api/
+	MaxLinksPerPage  int         // links queued from a single page at most, 0 for no limit. They are all still sent.
+	NoTrapDetection  bool        // follow urls that look like crawl traps, E.g. /a/b/a/b/a/b or calendars, instead of sending a Result with Source "trap"
+	SkipCDN          bool        // don't crawl the hosts in scope served by known CDNs, sending a Result with Source "cdn" for each
+	MaxSize          int         // in KB, -1 for colly's default
+	Binary           bool        // download binary files such as archives, PDFs and images, instead of skipping them
+	HeadFirst        bool        // send a HEAD request for every link, and only GET the HTML pages and scripts within MaxSize
+	FilterSizes      []SizeRange // pages with a body size in these ranges are skipped
+	MatchSizes       []SizeRange // only pages with a body size in these ranges are parsed, empty for all sizes
+	SubsInScope      bool
+	DisableRedirects bool
+	Threads          int
+	LimitRules       []LimitRule // limits for the hosts matching them, hosts matching none are limited by Threads
+	Proxy            *neturl.URL
+	IdleConnsPerHost int           // idle connections kept open to each host, 0 for Threads
+	NoKeepAlive      bool          // close connections after every request
+	TLSTimeout       time.Duration // time allowed for TLS handshakes, 0 for no limit
+	Insecure         bool
+	Timeout          int // in seconds, -1 for no timeout
+	RequestTimeout   int // in seconds for each request, 0 for no timeout
+	Hostname         string
+	Method           string          // method of the request for the URL crawled, empty for GET. Links found are always requested with GET.
+	Body             []byte          // body of the request for the URL crawled
+	TargetIP         string          // IP address connected to for hosts in scope instead of resolving them, may be empty
+	CacheDir         string          // directory responses to GET requests are cached in and reused from, empty for no cache
+	Validators       *Validators     // sends conditional requests for the pages seen by previous runs, may be nil
+	Storage          storage.Storage // shared visited set and cookies, nil for a per-URL in-memory store
+	Pauser           *Pauser         // holds back new requests while paused, may be nil
+	Context          context.Context // cancelling it stops the crawl, nil for context.Background()
+	Budget           *Budget         // limits the number of requests sent, may be nil
+	Stats            *Stats          // counts the requests sent and failed, may be nil
+	Unique           *URLSet         // sends each link once across the crawls sharing it, nil to send every one found
+	Breaker          *CircuitBreaker // skips the hosts that keep failing, may be nil
+	Proxies          *ProxyRotation  // proxies the requests to hosts that block them move on to, may be nil
+	DNSCache         *DNSCache       // resolves every host once for the whole run, nil to resolve on every connection
+	Bandwidth        *BandwidthLimit // caps the rate response bodies are read at, may be nil
+	ExcludeSubs      []string        // subdomains kept out of scope, E.g. dev or dev.example.com
+	Paths            []string        // path prefixes in scope, empty for all paths
+	RespectRobots    bool
+	RespectNofollow  bool                // print but don't follow the links marked rel=nofollow, and those of pages with a nofollow robots meta tag
+	Polite           bool                // honor Crawl-delay and Retry-After, implied by RespectRobots
+	DetectWAF        bool                // send a Result with Source "waf" for the hosts behind WAFs, and slow down for those blocking requests
+	RotateUserAgent  bool                // with DetectWAF, retry blocked requests with another browser's User-Agent
+	NoVisit          []string            // words in the path or query of URLs that are printed but never visited, DefaultNoVisit when nil, empty to visit everything
+	SessionParams    []string            // parameters holding session IDs, removed from links, E.g. DefaultSessionParams
+	StripTracking    bool                // remove tracking parameters such as utm_source and gclid from links
+	IgnoreCase       bool                // take urls whose paths only differ by case as the same when deduplicating and visiting
+	IgnoreSlash      bool                // take /path and /path/ as the same url when deduplicating and visiting
+	Subdomains       bool                // send a Result with Source "subdomain" for every new subdomain of Hostname seen
+	ThirdParty       *ThirdPartyDomains  // collects the out of scope domains referenced, may be nil
+	CORS             *CORSReport         // collects the CORS headers of endpoints, may be nil
+	Cookies          *CookieReport       // collects the cookies set by hosts, may be nil
+	CORSOrigin       string              // Origin header sent with every request to probe CORS, may be empty
+	Redirects        bool                // send a Result with Source "redirect" for every redirect chain followed, or with DisableRedirects, every redirect
+	FinalURLs        bool                // hold back the results for links until they are visited, to set FinalURL
+	OpenRedirects    bool                // also send a Result with Source "open-redirect" for URLs that look like redirectors
+	CheckLinks       bool                // send a Result with Source "broken" for every link that is dead, requesting those not crawled
+	DirListings      bool                // send a Result with Source "directory-listing" for every directory listing crawled
+	Routes           bool                // send a Result with Source "route" for every client-side route of a single page app found
+	InlineScripts    bool                // send a Result with Source "inline" for the JavaScript files referenced in inline scripts
+	Grep             *regexp.Regexp      // send a Result with Source "grep" for every match in the pages crawled, may be nil
+	Errors           bool                // send a Result with Source "error" for every request that got no response
+	OnResult         func(Result)        // called with every Result before it is sent, one at a time, may be nil
+	Extractors       []Extractor         // find more links, after the hrefs, JavaScript files and form actions
+	Hook             *ResponseHook       // script every response is handed to, may be nil
+	Middleware       []RequestMiddleware // applied in order to every request before it is sent
+	NTLM             *NTLMCredentials    // answer NTLM and Negotiate challenges of hosts in scope
+}
+
+// Crawl crawls url using the supplied config, sending every URL found to results. Programs embedding the crawler can
+// pass a nil results and get typed results through Config.OnResult instead.
+func Crawl(url string, config Config, results chan<- Result) {
+	// every result is tagged with the URL it was found from
+	found := make(chan Result)
+	forwarded := make(chan struct{})
+	go func(results chan<- Result) {
+		for res := range found {
+			res.Seed = url
+			if config.OnResult != nil {
+				config.OnResult(res)
+			}
+			if results != nil {
+				results <- res
+			}
+		}
+		close(forwarded)
+	}(results)
+	defer func() {
+		close(found)
+		<-forwarded
+	}()
+	results = found
+
+	// the context is cancelled when the timeout is reached or the parent context is done, aborting requests in flight
+	parent := config.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	if config.Timeout != -1 {
+		cancel()
+		ctx, cancel = context.WithTimeout(parent, time.Duration(config.Timeout)*time.Second)
+	}
+	defer cancel()
+
 	// Instantiate default collector
 	c := colly.NewCollector(
 		// default user agent header
-		colly.UserAgent("Mozilla/5.0 (X11; Linux x86_64; rv:78.0) Gecko/20100101 Firefox/78.0"),
+		colly.UserAgent(UserAgent),
 		// set custom headers
-		colly.Headers(headers),
+		colly.Headers(config.Headers),
 		// limit crawling to the domain of the specified URL
-		colly.AllowedDomains(allowedDomains...),
+		colly.AllowedDomains(config.AllowedDomains...),
 		// set MaxDepth to the specified depth
-		colly.MaxDepth(maxDepth),
+		colly.MaxDepth(config.MaxDepth),
 		// specify Async for threading
 		colly.Async(true),
+		// cancel requests once the timeout is reached
+		colly.StdlibContext(ctx),
 	)
 
-	// set a page size limit
-	if maxSize != -1 {
-		c.MaxBodySize = maxSize * 1024
+	// use the shared storage, if one is specified
+	if config.Storage != nil {
+		if err := c.SetStorage(config.Storage); err != nil {
+			log.Println("Error setting up storage:", err)
+			return
+		}
+	}
+
+	// reuse the responses cached by earlier runs, if -cache is present
+	if config.CacheDir != "" {
+		c.CacheDir = config.CacheDir
+	}
+
+	// obey robots.txt, if -respect-robots is present
+	if config.RespectRobots {
+		c.IgnoreRobotsTxt = false
+	}
+
+	// set a page size limit, enforced while the body is read so responses without a Content-Length are cut off too
+	var truncated *truncatedBodies
+	if config.MaxSize != -1 {
+		c.MaxBodySize = 0
+		truncated = newTruncatedBodies()
 	}
 
 	// if -subs is present, use regex to filter out subdomains in scope.
-	if subsInScope {
+	if config.SubsInScope {
 		c.AllowedDomains = nil
-		c.URLFilters = []*regexp.Regexp{regexp.MustCompile(".*(\\.|\\/\\/)" + strings.ReplaceAll(hostname, ".", "\\.") + "((#|\\/|\\?).*)?")}
+		c.URLFilters = []*regexp.Regexp{regexp.MustCompile(".*(\\.|\\/\\/)" + strings.ReplaceAll(config.Hostname, ".", "\\.") + "((#|\\/|\\?).*)?")}
+	}
+
+	// never visit URLs that look destructive, they are still printed
+	if config.NoVisit == nil {
+		config.NoVisit = DefaultNoVisit
+	}
+	if len(config.NoVisit) > 0 {
+		c.DisallowedURLFilters = append(c.DisallowedURLFilters, NoVisitFilter(config.NoVisit))
 	}
 
 	// If `-dr` flag provided, do not follow HTTP redirects.
-	if disableRedirects {
+	var redirects *redirectRecorder
+	if config.DisableRedirects {
 		c.SetRedirectHandler(func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		})
+	} else if config.Redirects || config.FinalURLs {
+		redirects = newRedirectRecorder()
+		c.SetRedirectHandler(redirects.handle)
+	}
+	// Set parallelism, the first rule matching a host applies to it
+	for _, rule := range config.LimitRules {
+		if err := c.Limit(&colly.LimitRule{DomainGlob: rule.DomainGlob, Parallelism: rule.Parallelism, Delay: rule.Delay}); err != nil {
+			log.Println("Error setting limit for "+rule.DomainGlob+":", err)
+		}
+	}
+	c.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: config.Threads})
+	var queue *frontier
+	if config.Strategy != "" || config.Prioritize {
+		slots := config.Threads
+		for _, rule := range config.LimitRules {
+			slots += rule.Parallelism
+		}
+		queue = newFrontier(config.Strategy, config.Prioritize, slots)
+		c.OnError(func(r *colly.Response, err error) {
+			queue.release(r.Request)
+		})
+		c.OnScraped(func(r *colly.Response) {
+			queue.release(r.Request)
+		})
 	}
-	// Set parallelism
-	c.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: threads})
 
-	// Print every href found, and visit it
-	c.OnHTML("a[href]", func(e *colly.HTMLElement) {
-		link := e.Attr("href")
-		abs_link := e.Request.AbsoluteURL(link)
-		if strings.Contains(abs_link, url) || !inside {
-			printResult(link, "href", showSource, showWhere, showJson, results, e)
-			e.Request.Visit(link)
+	// keep as many connections open to each host as there are threads, so they are reused rather than each request
+	// opening a new one. With high thread counts, that would exhaust ephemeral ports.
+	transport := &http.Transport{
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: config.Insecure},
+		MaxIdleConnsPerHost: config.IdleConnsPerHost,
+		DisableKeepAlives:   config.NoKeepAlive,
+		TLSHandshakeTimeout: config.TLSTimeout,
+	}
+	if transport.MaxIdleConnsPerHost == 0 {
+		transport.MaxIdleConnsPerHost = config.Threads
+	}
+
+	if config.Proxies != nil {
+		transport.Proxy = config.Proxies.proxyFunc(config.Proxy)
+	} else if config.Proxy != nil {
+		// Skip TLS verification for proxy, if -insecure specified
+		transport.Proxy = http.ProxyURL(config.Proxy)
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if config.NoKeepAlive {
+		dialer.KeepAlive = -1
+		transport.DialContext = dialer.DialContext
+	}
+	dial := dialer.DialContext
+	if config.DNSCache != nil {
+		dial = config.DNSCache.dialContext(dialer)
+		transport.DialContext = dial
+	}
+
+	// connect to the target IP for hosts in scope, so virtual hosts can be crawled before their DNS records exist.
+	// Requests and TLS still use the host name.
+	if config.TargetIP != "" {
+		transport.DialContext = func(ctx context.Context, network string, addr string) (net.Conn, error) {
+			if host, port, err := net.SplitHostPort(addr); err == nil && config.inScopeHost(host) {
+				addr = net.JoinHostPort(config.TargetIP, port)
+			}
+			return dial(ctx, network, addr)
+		}
+	}
+
+	// every request sent takes one from the budget, including those made outside of colly, E.g. for robots.txt, HEAD
+	// requests or -check-links
+	sent := config.Budget.Transport(transport)
+
+	// every request is sent through the middleware, including those made outside of colly, E.g. for robots.txt
+	base := sent
+	// requests to hosts out of scope that are made outside of colly, E.g. by -check-links, skip it
+	unauthenticated := sent
+	if config.NTLM != nil {
+		// other hosts could crack the password from the responses, so only those in scope are answered
+		base = &ntlmTransport{next: base, credentials: *config.NTLM, inScope: config.inScopeHost}
+	}
+	if len(config.Middleware) > 0 {
+		base = &middlewareTransport{next: base, middleware: config.Middleware}
+	}
+
+	out := &output{config: &config, results: results, parents: newParentTracker()}
+	if config.MaxLinksPerPage > 0 {
+		out.linkCounts = newLinkCounter(config.MaxLinksPerPage)
+	}
+	if !config.NoTrapDetection {
+		out.traps = newTrapDetector()
+	}
+	if config.SkipCDN {
+		out.cdns = newCDNDetector()
+	}
+	if config.IgnoreCase || config.IgnoreSlash {
+		out.folded = NewURLSet()
+		out.folded.add(config.foldURL(url))
+	}
+	if config.FinalURLs && !config.DisableRedirects {
+		out.pending = newPendingResults()
+	}
+	if config.CheckLinks {
+		out.links = newLinkChecker()
+		// the URL of a request is replaced by the one it redirected to, so the one requested is kept to record it too
+		requestedKey := func(r *colly.Request) string {
+			return "link-requested " + strconv.FormatUint(uint64(r.ID), 10)
+		}
+		c.OnRequest(func(r *colly.Request) {
+			r.Ctx.Put(requestedKey(r), r.URL.String())
+		})
+		visited := func(r *colly.Response, err error) {
+			out.links.visited(r.Request.URL.String(), r.StatusCode, err)
+			if requested := r.Ctx.Get(requestedKey(r.Request)); requested != "" && requested != r.Request.URL.String() {
+				out.links.visited(requested, r.StatusCode, err)
+			}
+		}
+		c.OnResponseHeaders(func(r *colly.Response) {
+			visited(r, nil)
+		})
+		c.OnError(func(r *colly.Response, err error) {
+			if err != colly.ErrAbortedAfterHeaders && ctx.Err() == nil {
+				visited(r, err)
+			}
+		})
+	}
+	roundTripper := base
+	if truncated != nil {
+		roundTripper = &sizeLimitTransport{next: roundTripper, limit: int64(config.MaxSize) * 1024, truncated: truncated}
+	}
+	if config.Bandwidth != nil {
+		roundTripper = &bandwidthTransport{next: roundTripper, limit: config.Bandwidth}
+	}
+	if config.Breaker != nil {
+		roundTripper = &breakerTransport{next: roundTripper, breaker: config.Breaker, ctx: ctx}
+	}
+	if config.Subdomains {
+		out.subdomains = newSubdomainTracker(config.Hostname, results)
+		// subdomains are also collected from the names in TLS certificates
+		roundTripper = &certNames{next: roundTripper, found: out.subdomains.add}
+	}
+	if config.DisableRedirects {
+		// print where redirects point to when they aren't followed, as a chain of one redirect
+		roundTripper = &unfollowedRedirects{next: roundTripper, found: func(from string, to string) {
+			out.emit(Result{
+				Source:    "redirect",
+				URL:       to,
+				Where:     from,
+				Redirects: []string{from, to},
+			})
+		}}
+	}
+	c.WithTransport(roundTripper)
+	if config.RequestTimeout > 0 {
+		c.SetRequestTimeout(time.Duration(config.RequestTimeout) * time.Second)
+	}
+
+	// report the requests that failed without a response, E.g. because of DNS, TLS or connection errors
+	if config.Errors {
+		c.OnError(func(r *colly.Response, err error) {
+			if r.StatusCode != 0 || err == colly.ErrAbortedAfterHeaders || ctx.Err() != nil || errors.Is(err, errBudgetSpent) {
+				return
+			}
+			results <- Result{
+				Source: "error",
+				URL:    r.Request.URL.String(),
+				Error:  err.Error(),
+				Kind:   ErrorKind(err),
+			}
+		})
+	}
+
+	// slow down for hosts answering 429/503, and space out requests per host according to Crawl-delay and Retry-After
+	// if -polite or -respect-robots is present
+	polite := config.Polite || config.RespectRobots
+	var limiter *hostLimiter
+	if polite {
+		limiter = newHostLimiter(&http.Client{Transport: base, Timeout: 10 * time.Second})
+	} else {
+		limiter = newHostLimiter(nil)
+	}
+	c.OnResponseHeaders(func(r *colly.Response) {
+		if r.StatusCode != http.StatusTooManyRequests && r.StatusCode != http.StatusServiceUnavailable {
+			limiter.recovered(r.Request.URL.Host)
 		}
 	})
+	c.OnError(func(r *colly.Response, err error) {
+		if r.StatusCode != http.StatusTooManyRequests && r.StatusCode != http.StatusServiceUnavailable {
+			return
+		}
+		limiter.limited(r.Request.URL.Host)
+		if !polite {
+			return
+		}
+		delay, ok := parseRetryAfter(r.Headers.Get("Retry-After"))
+		if !ok {
+			delay = 5 * time.Second
+		}
+		limiter.backoff(r.Request.URL.Host, delay)
 
-	// find and print all the JavaScript files
-	c.OnHTML("script[src]", func(e *colly.HTMLElement) {
-		printResult(e.Attr("src"), "script", showSource, showWhere, showJson, results, e)
+		// the context is shared with the requests for the links of the page, so the count is kept per url
+		key := "retries " + r.Request.URL.String()
+		retries, _ := r.Ctx.GetAny(key).(int)
+		if retries < maxRetries {
+			r.Ctx.Put(key, retries+1)
+			r.Request.Retry()
+		}
 	})
 
-	// find and print all the form action URLs
-	c.OnHTML("form[action]", func(e *colly.HTMLElement) {
-		printResult(e.Attr("action"), "form", showSource, showWhere, showJson, results, e)
+	// the seed is used to check whether links are inside its path, for -i
+	seedURL, _ := neturl.Parse(url)
+
+	// skip binary files once their headers are received, rather than downloading them. The other headers callbacks
+	// still see them, E.g. for -check-links.
+	if !config.Binary {
+		c.OnResponseHeaders(func(r *colly.Response) {
+			if isBinary(r.Headers.Get("Content-Type")) {
+				r.Request.Abort()
+			}
+		})
+	}
+
+	// mark the pages cut off at MaxSize, which are still parsed up to there
+	if truncated != nil {
+		c.OnResponse(func(r *colly.Response) {
+			if truncated.take(r.Request.URL.String()) {
+				out.emit(Result{Source: "truncated", URL: r.Request.URL.String(), Error: "larger than " + strconv.Itoa(config.MaxSize) + " KB"})
+			}
+		})
+		c.OnError(func(r *colly.Response, err error) {
+			truncated.take(r.Request.URL.String())
+		})
+	}
+
+	// skip the pages filtered by size, E.g. error pages that all have the same size. This callback is registered before
+	// the others, so emptying the body keeps them and the HTML callbacks from finding anything on the page.
+	if len(config.FilterSizes) > 0 || len(config.MatchSizes) > 0 {
+		c.OnResponse(func(r *colly.Response) {
+			if config.sizeFiltered(len(r.Body)) {
+				r.Body = nil
+				r.Headers.Del("Refresh")
+			}
+		})
+	}
+
+	// convert the pages in other encodings than UTF-8 that colly leaves as they are, before anything looks at them
+	c.OnResponse(toUTF8)
+
+	// hand the pages to the script of -hook, to find more links or skip them. Pages filtered by size are skipped
+	// already, and have no body.
+	if config.Hook != nil {
+		c.OnResponse(func(r *colly.Response) {
+			if r.Body == nil {
+				return
+			}
+			reply, err := config.Hook.call(r)
+			if err != nil {
+				log.Println("Error calling hook for "+r.Request.URL.String()+":", err)
+				return
+			}
+			for _, res := range reply.Results {
+				source := res.Source
+				if source == "" {
+					source = "hook"
+				}
+				link := r.Request.AbsoluteURL(res.URL)
+				out.send(link, source, r.Request.URL.String())
+				if res.Visit && (!config.Inside || isInside(link, seedURL)) {
+					out.visit(r.Request, link)
+				}
+			}
+			if reply.Skip {
+				r.Body = nil
+				r.Headers.Del("Refresh")
+			}
+		})
+	}
+
+	// print the links found by the extractors, the hrefs, JavaScript files and form actions and any in
+	// Config.Extractors, and visit those of the extractors that follow them. HTML pages are parsed once for all of
+	// them, and links on them are relative to their <base href>.
+	extractors := append(append(hrefExtractors(config.RespectNofollow), builtinExtractors...), config.Extractors...)
+	c.OnHTML("html", func(e *colly.HTMLElement) {
+		out.extract(extractors, &Response{Response: e.Response, DOM: e.DOM}, seedURL)
+	})
+	c.OnResponse(func(r *colly.Response) {
+		// the responses colly parses as HTML are handled above
+		if !strings.Contains(strings.ToLower(r.Headers.Get("Content-Type")), "html") {
+			out.extract(extractors, &Response{Response: r}, seedURL)
+		}
 	})
 
-	// add the custom headers
-	if headers != nil {
-		c.OnRequest(func(r *colly.Request) {
-			for header, value := range headers {
-				r.Headers.Set(header, value)
+	// find and print JavaScript files referenced from inline scripts, E.g. when they are loaded dynamically
+	if config.InlineScripts {
+		c.OnHTML("script:not([src])", func(e *colly.HTMLElement) {
+			for _, match := range jsFileRegex.FindAllStringSubmatch(e.Text, -1) {
+				out.sendResult(match[1], "inline", e)
 			}
 		})
 	}
 
-	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure},
+	// find and print the client-side routes of single page apps, which are never requested as the server doesn't know them
+	if config.Routes {
+		c.OnHTML("a[href^='#/'], a[href^='#!/']", func(e *colly.HTMLElement) {
+			out.send(hashRouteURL(e, e.Attr("href")), "route", e.Request.URL.String())
+		})
+		c.OnHTML("script:not([src])", func(e *colly.HTMLElement) {
+			for _, route := range scriptRoutes(e.Text) {
+				out.sendResult(route, "route", e)
+			}
+		})
 	}
 
-	if proxy != nil {
-		// Skip TLS verification for proxy, if -insecure specified
-		transport.Proxy = http.ProxyURL(proxy)
+	// find and print the targets of meta refreshes and Refresh headers, and visit them, as they are redirects
+	c.OnHTML("meta[http-equiv][content]", func(e *colly.HTMLElement) {
+		if !strings.EqualFold(e.Attr("http-equiv"), "refresh") {
+			return
+		}
+		if target, ok := refreshTarget(e.Attr("content")); ok && (!config.Inside || isInside(e.Request.AbsoluteURL(target), seedURL)) {
+			out.sendResult(target, "refresh", e)
+			out.visit(e.Request, target)
+		}
+	})
+	c.OnResponse(func(r *colly.Response) {
+		if target, ok := refreshTarget(r.Headers.Get("Refresh")); ok && (!config.Inside || isInside(r.Request.AbsoluteURL(target), seedURL)) {
+			out.send(r.Request.AbsoluteURL(target), "refresh", r.Request.URL.String())
+			out.visit(r.Request, target)
+		}
+	})
+
+	// find and print all the iframe sources
+	c.OnHTML("iframe[src]", func(e *colly.HTMLElement) {
+		out.sendResult(e.Attr("src"), "iframe", e)
+	})
+
+	// print the redirect chains that were followed, and the results held back until the URL they resolve to is known
+	if redirects != nil {
+		c.OnResponseHeaders(func(r *colly.Response) {
+			final := r.Request.URL.String()
+			original := final
+			if chain := redirects.take(final); chain != nil {
+				original = chain[0]
+				if config.Redirects {
+					out.results <- Result{
+						Source:    "redirect",
+						URL:       final,
+						Where:     original,
+						Redirects: chain,
+					}
+				}
+			}
+			if out.pending != nil {
+				for _, res := range out.pending.resolve(original, final) {
+					out.emit(res)
+				}
+			}
+		})
 	}
 
-	c.WithTransport(transport)
+	// print the directory listings found. Their entries are links, so they are queued for crawling like any other.
+	if config.DirListings {
+		c.OnResponse(func(r *colly.Response) {
+			if isDirListing(r.Body) {
+				out.emit(Result{
+					Source: "directory-listing",
+					URL:    r.Request.URL.String(),
+					Where:  r.Request.URL.String(),
+				})
+			}
+		})
+	}
 
-	if timeout == -1 {
-		// Start scraping
-		c.Visit(url)
-		// Wait until threads are finished
-		c.Wait()
-	} else {
-		finished := make(chan int, 1)
+	// print the pages matching -grep
+	if config.Grep != nil {
+		c.OnResponse(func(r *colly.Response) {
+			for _, excerpt := range grepExcerpts(config.Grep, r.Body) {
+				out.emit(Result{
+					Source: "grep",
+					URL:    r.Request.URL.String(),
+					Where:  r.Request.URL.String(),
+					Match:  excerpt,
+				})
+			}
+		})
+	}
 
-		go func() {
-			// Start scraping
-			c.Visit(url)
-			// Wait until threads are finished
-			c.Wait()
-			finished <- 0
-		}()
+	// find and print the hosts allowed by Content-Security-Policy headers
+	c.OnResponse(func(r *colly.Response) {
+		out.sendCSPHosts(r)
+	})
 
-		select {
-		case <-finished: // the crawling finished before the timeout
-			close(finished)
-		case <-time.After(time.Duration(timeout) * time.Second): // timeout reached
-			log.Println("[timeout] " + url)
+	// with -head-first, links found are checked with a HEAD request before downloading them. Like the links checked,
+	// the hosts out of scope they redirect to get no credentials.
+	var headClient *http.Client
+	if config.HeadFirst {
+		headClient = &http.Client{
+			Transport:     &scopedTransport{next: base, outOfScope: unauthenticated, inScope: config.inScopeHost},
+			Timeout:       10 * time.Second,
+			CheckRedirect: config.headFirstRedirect,
 		}
 	}
-}
 
-// print result constructs output lines and sends them to the results chan
-func printResult(link string, sourceName string, showSource bool, showWhere bool, showJson bool, results chan<- string, e *colly.HTMLElement) {
-	result := e.Request.AbsoluteURL(link)
-	whereURL := e.Request.URL.String()
-	if result != "" {
-		if showJson {
-			where := ""
-			if showWhere {
-				where = whereURL
-			}
-			bytes, _ := json.Marshal(Result{
-				Source: sourceName,
-				URL:    result,
-				Where:  where,
-			})
-			result = string(bytes)
-		} else if showSource {
-			result = "[" + sourceName + "] " + result
+	// hold back new requests while the crawl is paused, and drop them once the context is done, when they are out of scope
+	// or when the request budget is spent
+	c.OnRequest(func(r *colly.Request) {
+		if config.Pauser != nil {
+			config.Pauser.Wait(ctx)
+		}
+		if ctx.Err() != nil {
+			r.Abort()
+			return
+		}
+		// the seed itself is always requested, as it is the entry point to the paths in scope
+		if r.Depth > 1 && config.filtered(r.URL.String()) {
+			r.Abort()
+			return
+		}
+		// with -strategy or -prioritize, requests wait their turn before anything is spent on them
+		if queue != nil {
+			if !queue.wait(ctx, r) {
+				r.Abort()
+				return
+			}
+		}
+		// the budget is taken when the request is sent, so the requests made for it, E.g. HEAD requests, count too
+		if config.Budget != nil && config.Budget.Spent() {
+			if queue != nil {
+				queue.release(r)
+			}
+			r.Abort()
+			return
+		}
+		limiter.wait(ctx, r.URL.Scheme, r.URL.Host)
+		if config.Stats != nil {
+			config.Stats.request()
 		}
+		if headClient != nil && r.Depth > 1 && r.Method == http.MethodGet {
+			if status, ok := headFirst(ctx, headClient, &config, r.URL.String()); !ok {
+				if out.links != nil {
+					out.links.visited(r.URL.String(), status, nil)
+				}
+				if queue != nil {
+					queue.release(r)
+				}
+				r.Abort()
+			}
+		}
+	})
+	if config.Stats != nil {
+		c.OnError(func(r *colly.Response, err error) {
+			if err != colly.ErrAbortedAfterHeaders && r.StatusCode != http.StatusNotModified && ctx.Err() == nil {
+				config.Stats.failed()
+			}
+		})
+	}
+
+	// record CORS headers, probing with an Origin header if one is specified
+	if config.CORSOrigin != "" {
+		c.OnRequest(func(r *colly.Request) {
+			r.Headers.Set("Origin", config.CORSOrigin)
+		})
+	}
+	if config.CORS != nil {
+		c.OnResponseHeaders(func(r *colly.Response) {
+			config.CORS.add(r.Request.URL.String(), config.CORSOrigin, *r.Headers)
+		})
+	}
+
+	// record the cookies set
+	if config.Cookies != nil {
+		c.OnResponseHeaders(func(r *colly.Response) {
+			config.Cookies.add(r.Request.URL.String(), r.Request.URL.Hostname(), *r.Headers)
+		})
+	}
+
+	// request pages seen by previous runs conditionally, they aren't parsed again if they haven't changed
+	if config.Validators != nil {
+		c.OnRequest(func(r *colly.Request) {
+			config.Validators.setConditional(r.URL.String(), *r.Headers)
+		})
+		c.OnResponseHeaders(func(r *colly.Response) {
+			config.Validators.record(r.Request.URL.String(), r.StatusCode, *r.Headers)
+		})
+	}
+
+	// add the custom headers
+	if config.Headers != nil || config.HostHeaders != nil {
+		c.OnRequest(func(r *colly.Request) {
+			for header, value := range config.Headers {
+				r.Headers.Set(header, value)
+			}
+			config.setHostHeaders(*r.Headers, r.URL.Hostname())
+		})
+	}
 
-		if showWhere && !showJson {
-			result = "[" + whereURL + "] " + result
+	// move the hosts that block requests on to the next proxy, which the rest of their requests go through as well.
+	// WAF block pages are retried through it straight away. Hosts only move after a few 403 and 429 responses in a
+	// row, as a single one may be a page that is forbidden, and only the 429s are retried.
+	if config.Proxies != nil {
+		c.OnRequest(func(r *colly.Request) {
+			r.Ctx.Put("proxy "+r.URL.String(), config.Proxies.index(r.URL.Host))
+		})
+		proxyIndex := func(r *colly.Response) int {
+			index, _ := r.Ctx.GetAny("proxy " + r.Request.URL.String()).(int)
+			return index
+		}
+		check := func(r *colly.Response) {
+			host := r.Request.URL.Host
+			if _, blocked := detectWAF(r.StatusCode, *r.Headers, r.Body); blocked {
+				if config.Proxies.blocked(host, proxyIndex(r)) {
+					r.Request.Retry()
+				}
+				return
+			}
+			switch r.StatusCode {
+			case http.StatusForbidden, http.StatusTooManyRequests:
+				if config.Proxies.refused(host, proxyIndex(r)) && r.StatusCode == http.StatusTooManyRequests {
+					r.Request.Retry()
+				}
+			default:
+				config.Proxies.passed(host)
+			}
 		}
+		c.OnResponse(check)
+		c.OnError(func(r *colly.Response, err error) {
+			if r.StatusCode != 0 {
+				check(r)
+			}
+		})
+	}
 
-		// If timeout occurs before goroutines are finished, recover from panic that may occur when attempting writing to results to closed results channel
-		defer func() {
-			if err := recover(); err != nil {
+	// recognise WAFs, slowing down for the hosts whose WAF blocks requests. This is done after the custom headers are
+	// added, so the User-Agent they set is rotated as well.
+	if config.DetectWAF {
+		wafs := newWAFTracker()
+		check := func(r *colly.Response) {
+			if !wafs.check(out, r.Request.URL, r.StatusCode, *r.Headers, r.Body) {
 				return
 			}
-		}()
-		results <- result
+			limiter.limited(r.Request.URL.Host)
+			// the links of a page share its context, so retries are kept by url
+			key := "waf-retried " + r.Request.URL.String()
+			if retried, _ := r.Ctx.GetAny(key).(bool); config.RotateUserAgent && !retried {
+				r.Ctx.Put(key, true)
+				r.Request.Retry()
+			}
+		}
+		c.OnResponse(check)
+		c.OnError(func(r *colly.Response, err error) {
+			if r.StatusCode != 0 {
+				check(r)
+			}
+		})
+		if config.RotateUserAgent {
+			c.OnRequest(func(r *colly.Request) {
+				if userAgent := wafs.userAgent(r.URL.Host); userAgent != "" {
+					r.Headers.Set("User-Agent", userAgent)
+				}
+			})
+		}
+	}
+
+	// Start scraping
+	if config.Method != "" && config.Method != http.MethodGet {
+		c.Request(config.Method, url, bytes.NewReader(config.Body), nil, nil)
+	} else {
+		c.Visit(url)
+	}
+	// Wait until threads are finished, which happens promptly once the timeout cancels the context
+	c.Wait()
+	// send the results that never got a response, E.g. because the request failed
+	if out.pending != nil {
+		for _, res := range out.pending.flush() {
+			out.emit(res)
+		}
+	}
+	// check the links that weren't crawled, now that all of them are known
+	if out.links != nil {
+		checking := &scopedTransport{next: base, outOfScope: unauthenticated, inScope: config.inScopeHost}
+		out.links.check(ctx, &config, &http.Client{Transport: checking, Timeout: 10 * time.Second}, results)
+	}
+	if ctx.Err() == context.DeadlineExceeded && parent.Err() == nil {
+		log.Println("[timeout] " + url)
+	}
+}
+
+// sendCSPHosts sends the hosts found in the Content-Security-Policy headers of the response, and visits those in scope
+func (o *output) sendCSPHosts(r *colly.Response) {
+	where := r.Request.URL.String()
+	for _, header := range []string{"Content-Security-Policy", "Content-Security-Policy-Report-Only"} {
+		for _, policy := range r.Headers.Values(header) {
+			for _, source := range cspHostSources(policy) {
+				link, visitable := cspSourceURL(source, r.Request.URL.Scheme)
+				u, err := neturl.Parse(link)
+				if err != nil || u.Hostname() == "" {
+					continue
+				}
+				o.send(link, "csp", where)
+				if visitable && o.config.inScopeHost(u.Hostname()) {
+					o.visit(r.Request, link)
+				}
+			}
+		}
+	}
+}
+
+// output sends the results of a single crawl
+type output struct {
+	config     *Config
+	results    chan<- Result
+	subdomains *subdomainTracker // nil unless -show-subdomains is present
+	pending    *pendingResults   // nil unless -final-url is present
+	links      *linkChecker      // nil unless -check-links is present
+	parents    *parentTracker
+	linkCounts *linkCounter  // nil unless -max-links-per-page is present
+	traps      *trapDetector // nil with -no-trap-detection
+	cdns       *cdnDetector  // nil unless -skip-cdn is present
+	folded     *URLSet       // the visited urls as folded by foldURL, nil unless IgnoreCase or IgnoreSlash
+}
+
+// sendResult constructs a Result for the link and sends it to the results chan
+func (o *output) sendResult(link string, sourceName string, e *colly.HTMLElement) {
+	o.send(e.Request.AbsoluteURL(link), sourceName, e.Request.URL.String())
+}
+
+// sendResolved visits the link, holding back its Result until the URL it resolves to is known.
+// The Result is sent right away if the link won't be visited.
+func (o *output) sendResolved(r *colly.Request, res Result) {
+	res.URL = o.config.normalizeURL(res.URL)
+	if res.URL == "" || o.config.filtered(res.URL) {
+		return
+	}
+	o.parents.add(res.URL, res.Where)
+	if o.visit(r, res.URL) != nil {
+		o.emit(res)
+		return
+	}
+	o.pending.add(res)
+}
+
+// send sends a Result for an absolute URL found at where
+func (o *output) send(result string, sourceName string, where string) {
+	o.emit(Result{
+		Source: sourceName,
+		URL:    result,
+		Where:  where,
+	})
+}
+
+// emit sends res unless it is out of scope, recording its host for the subdomain and third party reports
+func (o *output) emit(res Result) {
+	res.URL = o.config.normalizeURL(res.URL)
+	if res.URL == "" || o.config.filtered(res.URL) {
+		return
+	}
+	if u, err := neturl.Parse(res.URL); err == nil && u.Hostname() != "" {
+		res.Scope = "out"
+		if o.config.inScopeHost(u.Hostname()) {
+			res.Scope = "in"
+		}
+	}
+	if res.Where != "" {
+		if isLink(res) {
+			o.parents.add(res.URL, res.Where)
+		}
+		res.Chain = o.parents.chain(res.Where)
+		res.Depth = len(res.Chain)
+	}
+	// links are sent once, findings about them such as redirects are sent whenever they are made
+	duplicate := o.config.Unique != nil && isLink(res) && !o.config.Unique.add(o.config.foldURL(res.URL))
+	if !duplicate {
+		o.results <- res
+	}
+	if o.config.OpenRedirects && !duplicate && res.Source != "open-redirect" && isOpenRedirectCandidate(res.URL) {
+		o.results <- Result{
+			Source: "open-redirect",
+			URL:    res.URL,
+			Where:  res.Where,
+			Scope:  res.Scope,
+		}
+	}
+	if o.links != nil && isLink(res) {
+		o.links.add(res.URL, res.Where)
+	}
+	if u, err := neturl.Parse(res.URL); err == nil {
+		if o.subdomains != nil {
+			o.subdomains.add(u.Hostname(), res.Where)
+		}
+		if o.config.ThirdParty != nil && !o.config.inScopeHost(u.Hostname()) {
+			o.config.ThirdParty.add(u.Hostname(), res.Source)
+		}
 	}
 }
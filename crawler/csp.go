@@ -0,0 +1,48 @@
+package crawler
+
+import (
+	"strings"
+)
+
+// cspHostSources extracts the host sources from a Content-Security-Policy, E.g. https://api.example.com or *.example.com
+func cspHostSources(policy string) []string {
+	var sources []string
+	for _, directive := range strings.Split(policy, ";") {
+		fields := strings.Fields(directive)
+		if len(fields) < 2 {
+			continue
+		}
+		switch strings.ToLower(fields[0]) {
+		case "report-uri", "report-to", "sandbox", "plugin-types", "require-trusted-types-for", "trusted-types":
+			// these don't take source lists
+			continue
+		}
+		for _, source := range fields[1:] {
+			// skip keywords such as 'self', schemes such as data: and catch-all sources
+			if strings.HasPrefix(source, "'") || strings.HasSuffix(source, ":") || source == "*" {
+				continue
+			}
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// cspSourceURL turns a host source into a URL, using scheme when the source has none.
+// Wildcard sources are returned for the domain they cover and reported as not visitable.
+func cspSourceURL(source string, scheme string) (string, bool) {
+	visitable := true
+	if i := strings.Index(source, "://"); i != -1 {
+		scheme, source = source[:i], source[i+3:]
+	}
+	if strings.HasPrefix(source, "*.") {
+		source = strings.TrimPrefix(source, "*.")
+		visitable = false
+	}
+	if strings.Contains(source, "*") {
+		// wildcard ports and paths can't be visited either
+		source = strings.ReplaceAll(strings.ReplaceAll(source, ":*", ""), "*", "")
+		visitable = false
+	}
+	return scheme + "://" + source, visitable
+}
@@ -0,0 +1,94 @@
+package crawler
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// DNSCache keeps the addresses hosts resolve to for the whole run, rather than resolving them for every connection
+type DNSCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*dnsEntry
+}
+
+type dnsEntry struct {
+	ready   chan struct{} // closed once the lookup is done
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+// NewDNSCache returns a DNSCache that keeps addresses for ttl
+func NewDNSCache(ttl time.Duration) *DNSCache {
+	return &DNSCache{ttl: ttl, entries: make(map[string]*dnsEntry)}
+}
+
+// lookup returns the addresses of host. Concurrent lookups of a host wait for the first one, and failed lookups
+// aren't cached.
+func (d *DNSCache) lookup(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+
+	d.mu.Lock()
+	entry, ok := d.entries[host]
+	if ok {
+		select {
+		case <-entry.ready:
+			if time.Now().After(entry.expires) {
+				ok = false
+			}
+		default:
+		}
+	}
+	if !ok {
+		entry = &dnsEntry{ready: make(chan struct{})}
+		d.entries[host] = entry
+		d.mu.Unlock()
+
+		entry.addrs, entry.err = net.DefaultResolver.LookupHost(ctx, host)
+		entry.expires = time.Now().Add(d.ttl)
+		if entry.err != nil {
+			d.mu.Lock()
+			if d.entries[host] == entry {
+				delete(d.entries, host)
+			}
+			d.mu.Unlock()
+		}
+		close(entry.ready)
+		return entry.addrs, entry.err
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-entry.ready:
+		return entry.addrs, entry.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// dialContext returns a DialContext function for transports, connecting with dialer to the cached addresses of hosts
+func (d *DNSCache) dialContext(dialer *net.Dialer) func(ctx context.Context, network string, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		addrs, err := d.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range addrs {
+			var conn net.Conn
+			conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
@@ -0,0 +1,35 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"strings"
+)
+
+// ErrorKind classifies why a request failed: dns, timeout, tls, connection, or skipped for the hosts skipped by a
+// CircuitBreaker and the requests sent once a Budget is spent
+func ErrorKind(err error) string {
+	if errors.Is(err, errHostSkipped) || errors.Is(err, errBudgetSpent) {
+		return "skipped"
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return "dns"
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return "timeout"
+	}
+	var recordErr tls.RecordHeaderError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certErr x509.CertificateInvalidError
+	if errors.As(err, &recordErr) || errors.As(err, &unknownAuthority) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &certErr) || strings.Contains(err.Error(), "tls: ") {
+		return "tls"
+	}
+	return "connection"
+}
@@ -0,0 +1,187 @@
+package crawler
+
+import (
+	"mime"
+	neturl "net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly/v2"
+)
+
+// Extractor finds links in the responses of the content types it handles. New sources of links, E.g. PDFs, JSON or
+// CSS, are added through Config.Extractors.
+type Extractor interface {
+	// MatchContentType reports whether Extract handles responses of a media type, E.g. text/html
+	MatchContentType(mediaType string) bool
+	// Extract returns the links found in a response. Their URL may be relative to the page, and Where is set to the
+	// page when it is empty.
+	Extract(resp *Response) []Result
+}
+
+// Follower is implemented by the extractors whose links are crawled, rather than only printed
+type Follower interface {
+	Follow() bool
+}
+
+// Response is a response handed to extractors
+type Response struct {
+	*colly.Response
+	DOM *goquery.Selection // the parsed document of HTML responses, nil for other content types
+}
+
+// htmlExtractor finds the links in an attribute of the elements matching a selector on HTML pages
+type htmlExtractor struct {
+	source   string
+	selector string
+	attr     string
+	follow   bool
+}
+
+func (x htmlExtractor) MatchContentType(mediaType string) bool {
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+func (x htmlExtractor) Extract(resp *Response) []Result {
+	var results []Result
+	resp.DOM.Find(x.selector).Each(func(_ int, s *goquery.Selection) {
+		if link, ok := s.Attr(x.attr); ok {
+			results = append(results, Result{Source: x.source, URL: link})
+		}
+	})
+	return results
+}
+
+func (x htmlExtractor) Follow() bool {
+	return x.follow
+}
+
+// formExtractor finds the forms of HTML pages, with their method and fields
+type formExtractor struct{}
+
+func (x formExtractor) MatchContentType(mediaType string) bool {
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+func (x formExtractor) Extract(resp *Response) []Result {
+	var results []Result
+	resp.DOM.Find("form[action]").Each(func(_ int, form *goquery.Selection) {
+		action, _ := form.Attr("action")
+		res := Result{Source: "form", URL: action, Method: strings.ToUpper(strings.TrimSpace(form.AttrOr("method", "")))}
+		if res.Method == "GET" {
+			res.Method = ""
+		}
+		var fields []string
+		form.Find("input[name], select[name], textarea[name]").Each(func(_ int, field *goquery.Selection) {
+			name, _ := field.Attr("name")
+			var value string
+			switch goquery.NodeName(field) {
+			case "select":
+				option := field.Find("option[selected]").First()
+				if option.Length() == 0 {
+					option = field.Find("option").First()
+				}
+				value = option.AttrOr("value", strings.TrimSpace(option.Text()))
+			case "textarea":
+				value = field.Text()
+			default:
+				// unchecked boxes aren't submitted
+				fieldType := strings.ToLower(field.AttrOr("type", ""))
+				if _, checked := field.Attr("checked"); (fieldType == "checkbox" || fieldType == "radio") && !checked {
+					return
+				}
+				value = field.AttrOr("value", "")
+			}
+			fields = append(fields, neturl.QueryEscape(name)+"="+neturl.QueryEscape(value))
+		})
+		res.Body = strings.Join(fields, "&")
+		results = append(results, res)
+	})
+	return results
+}
+
+// hrefExtractors returns the extractors of the links of a page. With respectNofollow, the links marked rel=nofollow
+// are printed but not followed.
+func hrefExtractors(respectNofollow bool) []Extractor {
+	if respectNofollow {
+		return []Extractor{
+			htmlExtractor{source: "href", selector: "a[href]:not([rel~=nofollow])", attr: "href", follow: true},
+			htmlExtractor{source: "href", selector: "a[href][rel~=nofollow]", attr: "href"},
+		}
+	}
+	return []Extractor{htmlExtractor{source: "href", selector: "a[href]", attr: "href", follow: true}}
+}
+
+// the extractors always used after the href ones, before those of Config.Extractors
+var builtinExtractors = []Extractor{
+	htmlExtractor{source: "script", selector: "script[src]", attr: "src"},
+	// JavaScript files that are preloaded
+	htmlExtractor{source: "script", selector: "link[rel=modulepreload][href], link[rel=preload][as=script][href]", attr: "href"},
+	formExtractor{},
+}
+
+// extract sends the links found by the extractors matching the content type of resp, and visits those of the
+// extractors that follow them
+func (o *output) extract(extractors []Extractor, resp *Response, seedURL *neturl.URL) {
+	mediaType, _, _ := mime.ParseMediaType(resp.Headers.Get("Content-Type"))
+	where := resp.Request.URL.String()
+	// pages asking for none of their links to be followed, with RespectNofollow
+	nofollow := o.config.RespectNofollow && isNofollowPage(resp)
+	for _, x := range extractors {
+		if !x.MatchContentType(mediaType) {
+			continue
+		}
+		follower, ok := x.(Follower)
+		follow := ok && follower.Follow() && !nofollow
+		for _, res := range x.Extract(resp) {
+			res.URL = resp.Request.AbsoluteURL(res.URL)
+			if res.Where == "" {
+				res.Where = where
+			}
+			switch {
+			case !follow:
+				o.emit(res)
+			case o.config.Inside && !isInside(res.URL, seedURL):
+			case o.pending != nil:
+				o.sendResolved(resp.Request, res)
+			default:
+				o.emit(res)
+				o.visit(resp.Request, res.URL)
+			}
+		}
+	}
+}
+
+// isNofollowPage reports whether a page asks robots not to follow its links, with an X-Robots-Tag header or a
+// <meta name="robots"> tag
+func isNofollowPage(resp *Response) bool {
+	for _, value := range resp.Headers.Values("X-Robots-Tag") {
+		if hasNofollow(value) {
+			return true
+		}
+	}
+	if resp.DOM == nil {
+		return false
+	}
+	nofollow := false
+	resp.DOM.Find("meta[name][content]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		if name, _ := s.Attr("name"); strings.EqualFold(name, "robots") {
+			content, _ := s.Attr("content")
+			nofollow = hasNofollow(content)
+		}
+		return !nofollow
+	})
+	return nofollow
+}
+
+// hasNofollow reports whether a robots directive, E.g. "noindex, nofollow", includes nofollow or none
+func hasNofollow(directives string) bool {
+	for _, directive := range strings.FieldsFunc(strings.ToLower(directives), func(r rune) bool {
+		return r == ',' || r == ' ' || r == ':'
+	}) {
+		if directive == "nofollow" || directive == "none" {
+			return true
+		}
+	}
+	return false
+}
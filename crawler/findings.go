@@ -0,0 +1,121 @@
+package crawler
+
+import (
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// matches the markers of directory listings generated by Apache, nginx, IIS, lighttpd and python's http.server
+var dirListingRegex = regexp.MustCompile(`(?i)<title>\s*(index of|directory listing for) /|\[to parent directory\]`)
+
+// sources of results that are findings about a URL rather than links found on a page
+var findingSources = map[string]bool{
+	"broken": true, "cdn": true, "directory-listing": true, "error": true, "grep": true, "open-redirect": true, "redirect": true, "subdomain": true, "trap": true, "truncated": true, "unchecked": true, "waf": true,
+}
+
+// IsFindingSource reports whether results with the given source are findings about a URL, E.g. a broken link,
+// rather than something referenced by a page
+func IsFindingSource(source string) bool {
+	return findingSources[source]
+}
+
+// isLink reports whether res is a link to an http(s) URL found on a page
+func isLink(res Result) bool {
+	return !IsFindingSource(res.Source) && (strings.HasPrefix(res.URL, "http://") || strings.HasPrefix(res.URL, "https://"))
+}
+
+// query parameter names commonly used to redirect after an action, E.g. /login?next=/account
+var redirectParams = map[string]bool{
+	"continue": true, "dest": true, "destination": true, "goto": true, "next": true, "redir": true,
+	"redirect": true, "redirect_uri": true, "redirect_url": true, "return": true, "return_to": true,
+	"returnto": true, "returnurl": true, "rurl": true, "target": true, "url": true,
+}
+
+// isOpenRedirectCandidate reports whether a query parameter of link holds an absolute or protocol-relative URL,
+// or a path in a parameter named like a redirect target
+func isOpenRedirectCandidate(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil || u.RawQuery == "" {
+		return false
+	}
+	for name, values := range u.Query() {
+		for _, value := range values {
+			v := strings.ToLower(strings.TrimSpace(value))
+			if strings.HasPrefix(v, "http://") || strings.HasPrefix(v, "https://") || strings.HasPrefix(v, "//") || strings.HasPrefix(v, `\/\/`) {
+				return true
+			}
+			if redirectParams[strings.ToLower(name)] && strings.HasPrefix(v, "/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isDirListing reports whether a response body is a generated directory listing
+func isDirListing(body []byte) bool {
+	return dirListingRegex.Match(body)
+}
+
+// most matches of -grep reported per page, and the characters of context shown around each
+const (
+	maxGrepMatches = 10
+	grepContext    = 40
+)
+
+// grepExcerpts returns the distinct matches of regex in body, each with some context around it on a single line
+func grepExcerpts(regex *regexp.Regexp, body []byte) []string {
+	var excerpts []string
+	seen := make(map[string]bool)
+	for _, loc := range regex.FindAllIndex(body, -1) {
+		match := string(body[loc[0]:loc[1]])
+		if seen[match] {
+			continue
+		}
+		seen[match] = true
+
+		start, end := loc[0]-grepContext, loc[1]+grepContext
+		if start < 0 {
+			start = 0
+		}
+		if end > len(body) {
+			end = len(body)
+		}
+		excerpt := strings.Join(strings.Fields(strings.ToValidUTF8(string(body[start:end]), "")), " ")
+		excerpts = append(excerpts, excerpt)
+		if len(excerpts) == maxGrepMatches {
+			break
+		}
+	}
+	return excerpts
+}
+
+// content types of binary files, which are skipped as soon as their headers are received
+var binaryContentTypes = map[string]bool{
+	"application/gzip": true, "application/java-archive": true, "application/msword": true,
+	"application/octet-stream": true, "application/pdf": true, "application/vnd.rar": true,
+	"application/wasm": true, "application/x-7z-compressed": true, "application/x-bzip2": true,
+	"application/x-gzip": true, "application/x-msdownload": true, "application/x-rar-compressed": true,
+	"application/x-shockwave-flash": true, "application/x-tar": true, "application/x-zip-compressed": true,
+	"application/zip": true,
+}
+
+// isBinary reports whether a Content-Type header is that of a binary file such as an archive, a PDF or an image.
+// SVG images are text and may contain links, so they aren't binary.
+func isBinary(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if mediaType == "image/svg+xml" {
+		return false
+	}
+	for _, prefix := range []string{"image/", "audio/", "video/", "font/", "application/vnd.ms-", "application/vnd.openxmlformats-"} {
+		if strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+	return binaryContentTypes[mediaType]
+}
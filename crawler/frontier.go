@@ -0,0 +1,185 @@
+package crawler
+
+import (
+	"container/heap"
+	"context"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// crawl orders for Config.Strategy
+const (
+	StrategyBreadth = "breadth" // pages closest to the seed first
+	StrategyDepth   = "depth"   // links of the page crawled last first
+)
+
+// paths of APIs, E.g. /api/users, /graphql or /v2/orders
+var apiPathRegex = regexp.MustCompile(`(?i)/(api|graphql|rest|v[0-9]+)(/|$)`)
+
+// pagination, E.g. /page/3 or ?page=3
+var paginationPathRegex = regexp.MustCompile(`(?i)/(page|p)/[0-9]+/?$`)
+
+var paginationParams = map[string]bool{"page": true, "p": true, "offset": true, "start": true, "pg": true}
+
+// extensions of files that are rarely more than what they look like
+var assetExtensions = map[string]bool{
+	"css": true, "png": true, "jpg": true, "jpeg": true, "gif": true, "svg": true, "ico": true, "webp": true,
+	"woff": true, "woff2": true, "ttf": true, "eot": true, "otf": true, "mp4": true, "mp3": true, "webm": true,
+}
+
+// extensions of pages and files that are usually generated, or hold data, rather than static content
+var interestingExtensions = map[string]bool{
+	"php": true, "asp": true, "aspx": true, "jsp": true, "jspx": true, "do": true, "action": true, "cgi": true,
+	"pl": true, "json": true, "xml": true, "yaml": true, "yml": true, "config": true, "bak": true, "sql": true,
+	"env": true, "txt": true, "log": true, "ashx": true, "asmx": true, "wsdl": true,
+}
+
+// urlPriority scores how likely the page at u is to be interesting, the higher the sooner it is crawled with
+// Config.Prioritize: parameters and API paths first, pagination and assets last
+func urlPriority(u *url.URL) int {
+	score := 0
+	query := u.Query()
+	if len(query) > 0 {
+		score += 2
+	}
+	if apiPathRegex.MatchString(u.Path) {
+		score += 3
+	}
+	extension := strings.ToLower(strings.TrimPrefix(path.Ext(u.Path), "."))
+	if interestingExtensions[extension] {
+		score++
+	}
+	if assetExtensions[extension] {
+		score -= 3
+	}
+	if paginationPathRegex.MatchString(u.Path) {
+		score -= 2
+	}
+	for name := range query {
+		if paginationParams[strings.ToLower(name)] {
+			// the parameters of pagination pages are what makes them uninteresting
+			score -= 4
+			break
+		}
+	}
+	return score
+}
+
+// frontier lets requests through in the order of a strategy, and of the priority of their urls. colly lets waiting requests through in no particular
+// order, so they queue here instead, for as many slots as colly sends requests at once.
+type frontier struct {
+	mu      sync.Mutex
+	free    int
+	seq     int
+	waiting frontierQueue
+	active  map[*colly.Request]bool
+}
+
+// frontierWaiter is a request waiting for a slot
+type frontierWaiter struct {
+	request   *colly.Request
+	priority  int
+	seq       int
+	ready     chan struct{}
+	cancelled bool
+}
+
+func newFrontier(strategy string, prioritize bool, slots int) *frontier {
+	return &frontier{
+		free:    slots,
+		waiting: frontierQueue{depthFirst: strategy == StrategyDepth, breadthFirst: strategy == StrategyBreadth, prioritize: prioritize},
+		active:  make(map[*colly.Request]bool),
+	}
+}
+
+// wait blocks until r may be sent, returning false if ctx is done first
+func (f *frontier) wait(ctx context.Context, r *colly.Request) bool {
+	f.mu.Lock()
+	f.seq++
+	if f.free > 0 && f.waiting.Len() == 0 {
+		f.free--
+		f.active[r] = true
+		f.mu.Unlock()
+		return true
+	}
+	w := &frontierWaiter{request: r, seq: f.seq, ready: make(chan struct{})}
+	if f.waiting.prioritize {
+		w.priority = urlPriority(r.URL)
+	}
+	heap.Push(&f.waiting, w)
+	f.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return true
+	case <-ctx.Done():
+		f.mu.Lock()
+		w.cancelled = true
+		f.mu.Unlock()
+		// the slot may have been given to it in the meantime
+		f.release(r)
+		return false
+	}
+}
+
+// release frees the slot of r, if it has one, for the next request in order
+func (f *frontier) release(r *colly.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.active[r] {
+		return
+	}
+	delete(f.active, r)
+	for f.waiting.Len() > 0 {
+		w := heap.Pop(&f.waiting).(*frontierWaiter)
+		if !w.cancelled {
+			f.active[w.request] = true
+			close(w.ready)
+			return
+		}
+	}
+	f.free++
+}
+
+// frontierQueue is a heap of the waiting requests, the next one to send first
+type frontierQueue struct {
+	waiters      []*frontierWaiter
+	depthFirst   bool
+	breadthFirst bool
+	prioritize   bool
+}
+
+func (q frontierQueue) Len() int { return len(q.waiters) }
+
+func (q frontierQueue) Less(i, j int) bool {
+	a, b := q.waiters[i], q.waiters[j]
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	if q.depthFirst {
+		// the deepest requests first, and of those the last found
+		if a.request.Depth != b.request.Depth {
+			return a.request.Depth > b.request.Depth
+		}
+		return a.seq > b.seq
+	}
+	if q.breadthFirst && a.request.Depth != b.request.Depth {
+		return a.request.Depth < b.request.Depth
+	}
+	return a.seq < b.seq
+}
+
+func (q frontierQueue) Swap(i, j int) { q.waiters[i], q.waiters[j] = q.waiters[j], q.waiters[i] }
+
+func (q *frontierQueue) Push(x interface{}) { q.waiters = append(q.waiters, x.(*frontierWaiter)) }
+
+func (q *frontierQueue) Pop() interface{} {
+	w := q.waiters[len(q.waiters)-1]
+	q.waiters = q.waiters[:len(q.waiters)-1]
+	return w
+}
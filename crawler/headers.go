@@ -0,0 +1,37 @@
+package crawler
+
+import (
+	"net/http"
+	"strings"
+)
+
+// HostHeader is a header sent only to some hosts, E.g. an API token for api.example.com
+type HostHeader struct {
+	Host  string // a host name, or *.example.com for example.com and its subdomains
+	Name  string
+	Value string
+}
+
+// matches reports whether the header is sent to host
+func (h HostHeader) matches(host string) bool {
+	return hostMatches(h.Host, host)
+}
+
+// hostMatches reports whether host is pattern, or one of its subdomains when pattern is *.example.com
+func hostMatches(pattern string, host string) bool {
+	host = strings.ToLower(host)
+	pattern = strings.ToLower(pattern)
+	if strings.HasPrefix(pattern, "*.") {
+		return host == pattern[2:] || strings.HasSuffix(host, pattern[1:])
+	}
+	return host == pattern
+}
+
+// setHostHeaders sets the headers configured for host, overriding the ones sent to every host
+func (config *Config) setHostHeaders(header http.Header, host string) {
+	for _, h := range config.HostHeaders {
+		if h.matches(host) {
+			header.Set(h.Name, h.Value)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// content types worth downloading with -head-first, those of the pages and scripts links are found in
+var crawlableContentTypes = map[string]bool{
+	"text/html": true, "application/xhtml+xml": true, "text/javascript": true, "application/javascript": true,
+	"application/x-javascript": true, "application/ecmascript": true, "text/ecmascript": true,
+}
+
+// headFirst sends a HEAD request for link and reports whether it is worth a GET: an HTML page or a script within
+// the size limit. Links are fetched anyway when the HEAD request fails or the server doesn't say what they are.
+func headFirst(ctx context.Context, client *http.Client, config *Config, link string) (int, bool) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, link, nil)
+	if err != nil {
+		return 0, true
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	// the custom headers often carry credentials, so only hosts in scope get them
+	if config.inScopeHost(req.URL.Hostname()) {
+		for header, value := range config.Headers {
+			req.Header.Set(header, value)
+		}
+	}
+	config.setHostHeaders(req.Header, req.URL.Hostname())
+	if host := req.Header.Get("Host"); host != "" {
+		req.Host = host
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, true
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		// servers that don't support HEAD may answer it with an error
+		return resp.StatusCode, true
+	}
+
+	if config.MaxSize != -1 {
+		if size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil && size > int64(config.MaxSize)*1024 {
+			return resp.StatusCode, false
+		}
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		return resp.StatusCode, true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return resp.StatusCode, err != nil || crawlableContentTypes[mediaType]
+}
+
+// headFirstRedirect follows the redirects of the HEAD requests, removing the custom headers from those leaving the
+// hosts in scope, which the client would otherwise copy from the request redirected
+func (config *Config) headFirstRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if !config.inScopeHost(req.URL.Hostname()) {
+		for header := range config.Headers {
+			req.Header.Del(header)
+		}
+	}
+	return nil
+}
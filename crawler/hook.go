@@ -0,0 +1,97 @@
+package crawler
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// ResponseHook hands every response to a script running alongside the crawl, so site-specific rules can be written
+// in any language. The script reads one JSON object per line on stdin:
+//
+//	{"url": "https://example.com/", "status": 200, "headers": {"Content-Type": ["text/html"]}, "body": "<html>.."}
+//
+// and answers each of them with one line on stdout, with the links it found and whether the page should be skipped:
+//
+//	{"skip": false, "results": [{"url": "/api/v2", "source": "custom", "visit": true}]}
+type ResponseHook struct {
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+type hookRequest struct {
+	URL     string      `json:"url"`
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body"`
+}
+
+type hookReply struct {
+	Skip    bool `json:"skip"` // skip the page: nothing else is extracted from it, and its links aren't followed
+	Results []struct {
+		URL    string `json:"url"` // may be relative to the page
+		Source string `json:"source"`
+		Visit  bool   `json:"visit"`
+	} `json:"results"`
+}
+
+// NewResponseHook starts command through the shell, E.g. "python3 hook.py"
+func NewResponseHook(command string) (*ResponseHook, error) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &ResponseHook{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)}, nil
+}
+
+// call sends a response to the script and reads its reply, one response at a time
+func (h *ResponseHook) call(r *colly.Response) (hookReply, error) {
+	var reply hookReply
+	line, err := json.Marshal(hookRequest{
+		URL:     r.Request.URL.String(),
+		Status:  r.StatusCode,
+		Headers: *r.Headers,
+		Body:    string(r.Body),
+	})
+	if err != nil {
+		return reply, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, err := h.stdin.Write(append(line, '\n')); err != nil {
+		return reply, err
+	}
+	answer, err := h.stdout.ReadBytes('\n')
+	if err != nil {
+		if err == io.EOF {
+			err = errors.New("script exited")
+		}
+		return reply, err
+	}
+	return reply, json.Unmarshal(answer, &reply)
+}
+
+// Close stops the script, letting it finish once its stdin is closed
+func (h *ResponseHook) Close() error {
+	h.stdin.Close()
+	return h.cmd.Wait()
+}
@@ -0,0 +1,203 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+
+	"github.com/temoto/robotstxt"
+)
+
+// linkChecker records the status of every link found, so the broken ones can be reported once the crawl is done
+type linkChecker struct {
+	mu      sync.Mutex
+	found   map[string]string // link -> where it was first found
+	order   []string
+	status  map[string]int                   // link -> status seen while crawling, 0 if the request failed
+	errors  map[string]string                // link -> error seen while crawling
+	skipped map[string]string                // link -> why it isn't requested
+	robots  map[string]*robotstxt.RobotsData // scheme://host -> its robots.txt, nil if it has none
+}
+
+func newLinkChecker() *linkChecker {
+	return &linkChecker{
+		found:   make(map[string]string),
+		status:  make(map[string]int),
+		errors:  make(map[string]string),
+		skipped: make(map[string]string),
+		robots:  make(map[string]*robotstxt.RobotsData),
+	}
+}
+
+// add records a link found at where
+func (lc *linkChecker) add(link string, where string) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if _, ok := lc.found[link]; !ok {
+		lc.found[link] = where
+		lc.order = append(lc.order, link)
+	}
+}
+
+// visited records the outcome of a request made while crawling
+func (lc *linkChecker) visited(link string, status int, err error) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.status[link] = status
+	if err != nil && status == 0 {
+		lc.errors[link] = err.Error()
+	}
+}
+
+// check requests every link that wasn't visited while crawling, then sends a Result with Source "broken" for each
+// link that returned 404, 410 or a server error, or couldn't be requested at all. Links the crawl wouldn't visit,
+// because they look destructive or robots.txt disallows them, aren't requested either and are sent with Source
+// "unchecked".
+func (lc *linkChecker) check(ctx context.Context, config *Config, client *http.Client, results chan<- Result) {
+	var noVisit *regexp.Regexp
+	if len(config.NoVisit) > 0 {
+		noVisit = NoVisitFilter(config.NoVisit)
+	}
+	lc.mu.Lock()
+	var unchecked []string
+	for _, link := range lc.order {
+		if _, ok := lc.status[link]; !ok {
+			unchecked = append(unchecked, link)
+		}
+	}
+	lc.mu.Unlock()
+
+	var requested []string
+	for _, link := range unchecked {
+		var reason string
+		if noVisit != nil && noVisit.MatchString(link) {
+			reason = "looks destructive to visit"
+		} else if config.RespectRobots && !lc.robotsAllowed(ctx, client, link) {
+			reason = "disallowed by robots.txt"
+		}
+		if reason == "" {
+			requested = append(requested, link)
+			continue
+		}
+		lc.mu.Lock()
+		lc.skipped[link] = reason
+		lc.mu.Unlock()
+	}
+
+	threads := config.Threads
+	if threads < 1 {
+		threads = 1
+	}
+	sem := make(chan struct{}, threads)
+	var wg sync.WaitGroup
+	for _, link := range requested {
+		if ctx.Err() != nil || (config.Budget != nil && config.Budget.Spent()) {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(link string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			status, err := requestStatus(ctx, client, config, link)
+			if ctx.Err() == nil {
+				lc.visited(link, status, err)
+				if config.Stats != nil {
+					config.Stats.request()
+					if err != nil || status >= 400 {
+						config.Stats.failed()
+					}
+				}
+			}
+		}(link)
+	}
+	wg.Wait()
+
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	for _, link := range lc.order {
+		if reason, ok := lc.skipped[link]; ok {
+			results <- Result{Source: "unchecked", URL: link, Where: lc.found[link], Error: reason}
+			continue
+		}
+		status, ok := lc.status[link]
+		if !ok || !isBroken(status) {
+			continue
+		}
+		results <- Result{
+			Source: "broken",
+			URL:    link,
+			Where:  lc.found[link],
+			Status: status,
+			Error:  lc.errors[link],
+		}
+	}
+}
+
+// robotsAllowed reports whether the robots.txt of the host of link allows our user agent to request it, fetching
+// robots.txt the first time the host is seen. Hosts without a robots.txt that can be read allow everything.
+func (lc *linkChecker) robotsAllowed(ctx context.Context, client *http.Client, link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return true
+	}
+	origin := u.Scheme + "://" + u.Host
+	lc.mu.Lock()
+	robots, ok := lc.robots[origin]
+	lc.mu.Unlock()
+	if !ok {
+		if req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil); err == nil {
+			req.Header.Set("User-Agent", UserAgent)
+			if resp, err := client.Do(req); err == nil {
+				robots, _ = robotstxt.FromResponse(resp)
+				resp.Body.Close()
+			}
+		}
+		lc.mu.Lock()
+		lc.robots[origin] = robots
+		lc.mu.Unlock()
+	}
+	return robots == nil || robots.TestAgent(u.EscapedPath(), UserAgent)
+}
+
+// isBroken reports whether a status means the link is dead. Status 0 means the request failed.
+func isBroken(status int) bool {
+	return status == 0 || status == http.StatusNotFound || status == http.StatusGone || status >= 500
+}
+
+// requestStatus sends a HEAD request for link, falling back to GET for servers that don't support HEAD
+func requestStatus(ctx context.Context, client *http.Client, config *Config, link string) (int, error) {
+	status, err := doStatus(ctx, client, config, http.MethodHead, link)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = doStatus(ctx, client, config, http.MethodGet, link)
+	}
+	return status, err
+}
+
+func doStatus(ctx context.Context, client *http.Client, config *Config, method string, link string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, link, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	// the custom headers often carry credentials, so only hosts in scope get them
+	if config.inScopeHost(req.URL.Hostname()) {
+		for header, value := range config.Headers {
+			req.Header.Set(header, value)
+		}
+	}
+	config.setHostHeaders(req.Header, req.URL.Hostname())
+	if host := req.Header.Get("Host"); host != "" {
+		req.Host = host
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,42 @@
+package crawler
+
+import "net/http"
+
+// RequestMiddleware changes a request before it is sent, E.g. to sign it, rotate headers or add per-host cookies.
+// It gets a copy of the request with every header colly sets, cookies included, and may replace its body.
+type RequestMiddleware func(req *http.Request) error
+
+// middlewareTransport applies middleware to every request, in order, before sending it
+type middlewareTransport struct {
+	next       http.RoundTripper
+	middleware []RequestMiddleware
+}
+
+func (t *middlewareTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// a RoundTripper mustn't modify the request it is given
+	req = req.Clone(req.Context())
+	for _, middleware := range t.middleware {
+		if err := middleware(req); err != nil {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, err
+		}
+	}
+	return t.next.RoundTrip(req)
+}
+
+// scopedTransport sends the requests to hosts in scope through next, and the others through outOfScope, E.g. so the
+// links checked on third party hosts don't get credentials set by middleware
+type scopedTransport struct {
+	next       http.RoundTripper
+	outOfScope http.RoundTripper
+	inScope    func(host string) bool
+}
+
+func (t *scopedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.inScope(req.URL.Hostname()) {
+		return t.next.RoundTrip(req)
+	}
+	return t.outOfScope.RoundTrip(req)
+}
@@ -0,0 +1,106 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+)
+
+// DefaultSessionParams are the parameters commonly holding session IDs, which make every page look new to the crawl
+var DefaultSessionParams = []string{"PHPSESSID", "JSESSIONID", "ASPSESSIONID", "CFID", "CFTOKEN", "sid", "sessionid", "session_id"}
+
+// parameters added by ad and analytics platforms to track where visitors come from, besides those starting with utm_
+var trackingParams = map[string]bool{
+	"gclid": true, "gbraid": true, "wbraid": true, "dclid": true, "fbclid": true, "msclkid": true, "yclid": true,
+	"twclid": true, "ttclid": true, "igshid": true, "li_fat_id": true, "mc_cid": true, "mc_eid": true, "_ga": true,
+	"_gl": true, "_hsenc": true, "_hsmi": true, "mkt_tok": true, "oly_anon_id": true, "oly_enc_id": true,
+	"vero_id": true, "wickedid": true,
+}
+
+// isTrackingParam reports whether name is a tracking parameter, E.g. utm_source or gclid
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasPrefix(name, "utm_") || trackingParams[name]
+}
+
+// normalizeURL returns link with the parts that don't change the page it points to removed, so it is sent and
+// visited once: the SessionParams in its query and path parameters, E.g. ?PHPSESSID=0123 or /cart;jsessionid=0123,
+// and with StripTracking the tracking parameters in its query, E.g. ?utm_source=newsletter
+func (config *Config) normalizeURL(link string) string {
+	if (len(config.SessionParams) == 0 && !config.StripTracking) || !strings.ContainsAny(link, "?;") {
+		return link
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	changed := false
+
+	if path := u.EscapedPath(); strings.Contains(path, ";") {
+		segments := strings.Split(path, "/")
+		for i, segment := range segments {
+			params := strings.Split(segment, ";")
+			kept := params[:1]
+			for _, param := range params[1:] {
+				if config.isSessionParam(strings.SplitN(param, "=", 2)[0]) {
+					changed = true
+				} else {
+					kept = append(kept, param)
+				}
+			}
+			segments[i] = strings.Join(kept, ";")
+		}
+		if changed {
+			u.RawPath = strings.Join(segments, "/")
+			u.Path, _ = url.PathUnescape(u.RawPath)
+		}
+	}
+
+	if u.RawQuery != "" {
+		var kept []string
+		for _, pair := range strings.Split(u.RawQuery, "&") {
+			name, err := url.QueryUnescape(strings.SplitN(pair, "=", 2)[0])
+			if err == nil && (config.isSessionParam(name) || (config.StripTracking && isTrackingParam(name))) {
+				changed = true
+			} else {
+				kept = append(kept, pair)
+			}
+		}
+		u.RawQuery = strings.Join(kept, "&")
+	}
+
+	if !changed {
+		return link
+	}
+	return u.String()
+}
+
+// isSessionParam reports whether name is one of the SessionParams, whatever its case
+func (config *Config) isSessionParam(name string) bool {
+	for _, param := range config.SessionParams {
+		if strings.EqualFold(name, param) {
+			return true
+		}
+	}
+	return false
+}
+
+// foldURL returns the key link is deduplicated and visited by, with its path lowercased with IgnoreCase and its
+// trailing slash removed with IgnoreSlash. The url itself is sent and requested as found.
+func (config *Config) foldURL(link string) string {
+	if !config.IgnoreCase && !config.IgnoreSlash {
+		return link
+	}
+	u, err := url.Parse(canonicalURL(link))
+	if err != nil {
+		return link
+	}
+	if config.IgnoreCase {
+		u.Path = strings.ToLower(u.Path)
+		u.RawPath = strings.ToLower(u.RawPath)
+	}
+	if config.IgnoreSlash && u.Path != "/" {
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		u.RawPath = strings.TrimSuffix(u.RawPath, "/")
+	}
+	return u.String()
+}
@@ -0,0 +1,231 @@
+package crawler
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// NTLMCredentials are the Windows account requests are authenticated as, for hosts asking for NTLM or Negotiate
+type NTLMCredentials struct {
+	Domain   string
+	User     string
+	Password string
+}
+
+// flags of the NTLM messages
+const (
+	ntlmUnicode         = 1 << 0
+	ntlmRequestTarget   = 1 << 2
+	ntlmNTLM            = 1 << 9
+	ntlmAlwaysSign      = 1 << 15
+	ntlmExtendedSession = 1 << 19
+	ntlmTargetInfo      = 1 << 23
+	ntlm128             = 1 << 29
+	ntlm56              = 1 << 31
+
+	ntlmFlags = ntlmUnicode | ntlmRequestTarget | ntlmNTLM | ntlmAlwaysSign | ntlmExtendedSession | ntlmTargetInfo |
+		ntlm128 | ntlm56
+)
+
+// ntlmTransport answers NTLM challenges. NTLM authenticates connections rather than requests, so the handshake
+// relies on the connection being reused for each of its requests, which keep-alive connections are.
+type ntlmTransport struct {
+	next        http.RoundTripper
+	credentials NTLMCredentials
+	inScope     func(host string) bool
+}
+
+func (t *ntlmTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !t.inScope(req.URL.Hostname()) {
+		return resp, err
+	}
+	scheme := ntlmScheme(resp.Header.Values("WWW-Authenticate"))
+	// requests with a body can only be sent again when it can be read again
+	if scheme == "" || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return resp, nil
+	}
+	drainBody(resp)
+
+	negotiate, err := ntlmRequest(req, scheme+" "+base64.StdEncoding.EncodeToString(ntlmNegotiate()))
+	if err != nil {
+		return nil, err
+	}
+	resp, err = t.next.RoundTrip(negotiate)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge, ok := ntlmChallenge(resp.Header.Values("WWW-Authenticate"), scheme)
+	if !ok {
+		return resp, nil
+	}
+	message, err := ntlmAuthenticate(t.credentials, challenge)
+	if err != nil {
+		return resp, nil
+	}
+	drainBody(resp)
+
+	authenticate, err := ntlmRequest(req, scheme+" "+base64.StdEncoding.EncodeToString(message))
+	if err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(authenticate)
+}
+
+// drainBody reads what is left of a response, so its connection is reused for the next step of the handshake
+func drainBody(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	resp.Body.Close()
+}
+
+// ntlmRequest returns a copy of req with its body rewound and the Authorization header set
+func ntlmRequest(req *http.Request, authorization string) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		clone.Body = body
+	}
+	clone.Header.Set("Authorization", authorization)
+	return clone, nil
+}
+
+// ntlmScheme returns the scheme NTLM can be negotiated with out of those a server offers, NTLM or Negotiate, which
+// falls back to NTLM when the client has no Kerberos ticket
+func ntlmScheme(offered []string) string {
+	for _, preferred := range []string{"NTLM", "Negotiate"} {
+		for _, value := range offered {
+			if strings.EqualFold(strings.TrimSpace(value), preferred) {
+				return preferred
+			}
+		}
+	}
+	return ""
+}
+
+// ntlmChallenge returns the challenge message a server sent in the WWW-Authenticate header for scheme
+func ntlmChallenge(values []string, scheme string) ([]byte, bool) {
+	for _, value := range values {
+		fields := strings.Fields(value)
+		if len(fields) == 2 && strings.EqualFold(fields[0], scheme) {
+			challenge, err := base64.StdEncoding.DecodeString(fields[1])
+			if err == nil && len(challenge) >= 32 && bytes.HasPrefix(challenge, []byte("NTLMSSP\x00")) &&
+				binary.LittleEndian.Uint32(challenge[8:]) == 2 {
+				return challenge, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// ntlmNegotiate returns the first message of the handshake, without a domain or workstation
+func ntlmNegotiate() []byte {
+	message := make([]byte, 32)
+	copy(message, "NTLMSSP\x00")
+	binary.LittleEndian.PutUint32(message[8:], 1)
+	binary.LittleEndian.PutUint32(message[12:], ntlmFlags)
+	return message
+}
+
+// ntlmAuthenticate returns the NTLMv2 response to a challenge message
+func ntlmAuthenticate(credentials NTLMCredentials, challenge []byte) ([]byte, error) {
+	flags := binary.LittleEndian.Uint32(challenge[20:])
+	serverChallenge := challenge[24:32]
+	var targetInfo []byte
+	if len(challenge) >= 48 {
+		length := int(binary.LittleEndian.Uint16(challenge[40:]))
+		offset := int(binary.LittleEndian.Uint32(challenge[44:]))
+		if offset+length > len(challenge) {
+			return nil, errors.New("malformed NTLM challenge")
+		}
+		targetInfo = challenge[offset : offset+length]
+	}
+
+	clientChallenge := make([]byte, 8)
+	if _, err := rand.Read(clientChallenge); err != nil {
+		return nil, err
+	}
+	timestamp, serverTime := ntlmTimestamp(targetInfo)
+	lm, nt := ntlmV2Responses(credentials, serverChallenge, clientChallenge, timestamp, targetInfo)
+	if serverTime {
+		// when the server sends its time, the LM response must be empty
+		lm = make([]byte, 24)
+	}
+
+	fields := [][]byte{lm, nt, utf16LE(credentials.Domain), utf16LE(credentials.User), nil, nil}
+	message := make([]byte, 64)
+	copy(message, "NTLMSSP\x00")
+	binary.LittleEndian.PutUint32(message[8:], 3)
+	for i, field := range fields {
+		header := message[12+i*8:]
+		binary.LittleEndian.PutUint16(header, uint16(len(field)))
+		binary.LittleEndian.PutUint16(header[2:], uint16(len(field)))
+		binary.LittleEndian.PutUint32(header[4:], uint32(len(message)))
+		message = append(message, field...)
+	}
+	binary.LittleEndian.PutUint32(message[60:], flags&ntlmFlags)
+	return message, nil
+}
+
+// ntlmV2Responses returns the LMv2 and NTLMv2 responses (MS-NLMP 3.3.2)
+func ntlmV2Responses(credentials NTLMCredentials, serverChallenge []byte, clientChallenge []byte, timestamp []byte, targetInfo []byte) ([]byte, []byte) {
+	key := hmacMD5(md4(utf16LE(credentials.Password)), utf16LE(strings.ToUpper(credentials.User)+credentials.Domain))
+
+	var blob []byte
+	blob = append(blob, 1, 1, 0, 0, 0, 0, 0, 0)
+	blob = append(blob, timestamp...)
+	blob = append(blob, clientChallenge...)
+	blob = append(blob, 0, 0, 0, 0)
+	blob = append(blob, targetInfo...)
+	blob = append(blob, 0, 0, 0, 0)
+
+	proof := hmacMD5(key, append(append([]byte{}, serverChallenge...), blob...))
+	lm := append(hmacMD5(key, append(append([]byte{}, serverChallenge...), clientChallenge...)), clientChallenge...)
+	return lm, append(proof, blob...)
+}
+
+// ntlmTimestamp returns the server time in the target info when there is one, as it must then be used, or the current
+// time, both in 100 nanoseconds since 1601
+func ntlmTimestamp(targetInfo []byte) ([]byte, bool) {
+	for len(targetInfo) >= 4 {
+		id := binary.LittleEndian.Uint16(targetInfo)
+		length := int(binary.LittleEndian.Uint16(targetInfo[2:]))
+		if id == 0 || len(targetInfo) < 4+length {
+			break
+		}
+		if id == 7 && length == 8 {
+			return targetInfo[4:12], true
+		}
+		targetInfo = targetInfo[4+length:]
+	}
+	timestamp := make([]byte, 8)
+	binary.LittleEndian.PutUint64(timestamp, uint64(time.Now().UnixNano()/100+116444736000000000))
+	return timestamp, false
+}
+
+func utf16LE(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	encoded := make([]byte, len(units)*2)
+	for i, unit := range units {
+		binary.LittleEndian.PutUint16(encoded[i*2:], unit)
+	}
+	return encoded
+}
+
+func hmacMD5(key []byte, data []byte) []byte {
+	mac := hmac.New(md5.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
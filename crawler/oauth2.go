@@ -0,0 +1,135 @@
+package crawler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokens are renewed this long before they expire, so none expires on the way to the server
+const oauth2ExpiryMargin = 30 * time.Second
+
+// OAuth2Config is an OAuth2 client credentials grant, E.g. for crawling a machine to machine API
+type OAuth2Config struct {
+	TokenURL     string            `json:"token_url"`
+	ClientID     string            `json:"client_id"`
+	ClientSecret string            `json:"client_secret"`
+	Scopes       []string          `json:"scopes"`
+	Params       map[string]string `json:"params"`       // extra parameters of the token request, E.g. audience
+	AuthInBody   bool              `json:"auth_in_body"` // send the client id and secret as parameters rather than basic auth
+	Hosts        []string          `json:"hosts"`        // hosts the token is sent to, E.g. *.example.com, or every host when empty
+}
+
+// OAuth2Token is the access token of a client credentials grant, renewed when it expires
+type OAuth2Token struct {
+	config OAuth2Config
+	client *http.Client
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time // zero when the server didn't say
+}
+
+// NewOAuth2Token gets a token from config.TokenURL with client, so bad credentials are reported before crawling
+func NewOAuth2Token(config OAuth2Config, client *http.Client) (*OAuth2Token, error) {
+	if config.TokenURL == "" || config.ClientID == "" {
+		return nil, errors.New("no token_url or client_id specified")
+	}
+	t := &OAuth2Token{config: config, client: client}
+	if _, err := t.get(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Authorize is a RequestMiddleware that sets the Authorization header of requests to the configured hosts
+func (t *OAuth2Token) Authorize(req *http.Request) error {
+	if len(t.config.Hosts) > 0 {
+		matched := false
+		for _, pattern := range t.config.Hosts {
+			if hostMatches(pattern, req.URL.Hostname()) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil
+		}
+	}
+	token, err := t.get()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// get returns the current token, requesting a new one if it is about to expire. Requests wait while it is renewed.
+func (t *OAuth2Token) get() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token != "" && (t.expiry.IsZero() || time.Now().Before(t.expiry)) {
+		return t.token, nil
+	}
+
+	params := url.Values{"grant_type": {"client_credentials"}}
+	if len(t.config.Scopes) > 0 {
+		params.Set("scope", strings.Join(t.config.Scopes, " "))
+	}
+	for name, value := range t.config.Params {
+		params.Set(name, value)
+	}
+	if t.config.AuthInBody {
+		params.Set("client_id", t.config.ClientID)
+		params.Set("client_secret", t.config.ClientSecret)
+	}
+	req, err := http.NewRequest(http.MethodPost, t.config.TokenURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if !t.config.AuthInBody {
+		req.SetBasicAuth(url.QueryEscape(t.config.ClientID), url.QueryEscape(t.config.ClientSecret))
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	var reply struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresIn   json.Number `json:"expires_in"`
+		Error       string      `json:"error"`
+	}
+	json.Unmarshal(body, &reply)
+	if resp.StatusCode != http.StatusOK || reply.AccessToken == "" {
+		if reply.Error != "" {
+			return "", fmt.Errorf("token request failed with status %s: %s", resp.Status, reply.Error)
+		}
+		return "", fmt.Errorf("token request failed with status %s", resp.Status)
+	}
+
+	t.token = reply.AccessToken
+	t.expiry = time.Time{}
+	if seconds, err := reply.ExpiresIn.Float64(); err == nil && seconds > 0 {
+		lifetime := time.Duration(seconds * float64(time.Second))
+		margin := oauth2ExpiryMargin
+		if lifetime < 2*margin {
+			margin = lifetime / 2
+		}
+		t.expiry = time.Now().Add(lifetime - margin)
+	}
+	return t.token, nil
+}
@@ -0,0 +1,70 @@
+package crawler
+
+import (
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// errTooManyLinks is the error of visits beyond Config.MaxLinksPerPage
+var errTooManyLinks = errors.New("too many links queued from the page")
+
+// errVisitedVariant is the error of visits to urls only differing from one visited by what IgnoreCase and
+// IgnoreSlash ignore
+var errVisitedVariant = errors.New("a variant of the url was visited")
+
+// linkCounter counts the links queued from each page, so pages listing thousands of links can't flood the crawl
+type linkCounter struct {
+	mu     sync.Mutex
+	max    int
+	counts map[string]int // page -> links queued from it
+}
+
+func newLinkCounter(max int) *linkCounter {
+	return &linkCounter{max: max, counts: make(map[string]int)}
+}
+
+// visit queues link from the page of r with visit, unless max links were queued from it already
+func (l *linkCounter) visit(r *colly.Request, link string, visit func(*colly.Request, string) error) error {
+	page := r.URL.String()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.counts[page] >= l.max {
+		if l.counts[page] == l.max {
+			log.Println("[max-links-per-page] reached, not following the other links on " + page)
+			l.counts[page]++
+		}
+		return errTooManyLinks
+	}
+	err := visit(r, link)
+	if err == nil {
+		l.counts[page]++
+	}
+	return err
+}
+
+// visit queues link from the page of r once normalized, within the links per page limit and unless it is in a crawl
+// trap or, with SkipCDN, on a CDN
+func (o *output) visit(r *colly.Request, link string) error {
+	link = o.config.normalizeURL(r.AbsoluteURL(link))
+	if o.trapped(r.URL.String(), link) {
+		return errTrapped
+	}
+	if o.onCDN(r.URL.String(), link) {
+		return errCDN
+	}
+	if o.linkCounts == nil {
+		return o.visitOnce(r, link)
+	}
+	return o.linkCounts.visit(r, link, o.visitOnce)
+}
+
+// visitOnce queues link unless it was queued written differently, E.g. with another case with IgnoreCase
+func (o *output) visitOnce(r *colly.Request, link string) error {
+	if o.folded != nil && !o.folded.add(o.config.foldURL(link)) {
+		return errVisitedVariant
+	}
+	return r.Visit(link)
+}
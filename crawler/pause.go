@@ -0,0 +1,52 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+)
+
+// Pauser holds back new requests while paused. Requests already in flight are allowed to finish.
+type Pauser struct {
+	mu      sync.Mutex
+	paused  bool
+	resumed chan struct{} // closed by Resume, replaced by each Pause
+}
+
+// NewPauser returns a Pauser in the running state
+func NewPauser() *Pauser {
+	return &Pauser{}
+}
+
+// Pause stops new requests from being sent until Resume is called
+func (p *Pauser) Pause() {
+	p.mu.Lock()
+	if !p.paused {
+		p.paused = true
+		p.resumed = make(chan struct{})
+	}
+	p.mu.Unlock()
+}
+
+// Resume lets held back requests continue
+func (p *Pauser) Resume() {
+	p.mu.Lock()
+	if p.paused {
+		p.paused = false
+		close(p.resumed)
+	}
+	p.mu.Unlock()
+}
+
+// Wait blocks for as long as the Pauser is paused, or until ctx is done, E.g. when the timeout is reached
+func (p *Pauser) Wait(ctx context.Context) {
+	p.mu.Lock()
+	paused, resumed := p.paused, p.resumed
+	p.mu.Unlock()
+	if !paused {
+		return
+	}
+	select {
+	case <-resumed:
+	case <-ctx.Done():
+	}
+}
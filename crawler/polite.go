@@ -0,0 +1,152 @@
+package crawler
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+)
+
+// maximum number of times a request is retried after a 429/503 response
+const maxRetries = 2
+
+// delays between requests to hosts that rate limit us. The delay starts at initialThrottle and doubles with every
+// 429/503 response, and shrinks by a tenth with every other response until it drops below a tenth of initialThrottle.
+const (
+	initialThrottle = time.Second
+	maxThrottle     = time.Minute
+)
+
+// hostLimiter spaces out requests to each host, using delays taken from robots.txt and Retry-After headers, and
+// slowing down for hosts that rate limit us
+type hostLimiter struct {
+	mu       sync.Mutex
+	client   *http.Client // fetches robots.txt, nil to not look for a Crawl-delay
+	checked  map[string]bool
+	delay    map[string]time.Duration
+	throttle map[string]time.Duration
+	slowed   map[string]time.Time // when the throttle of a host last went up
+	next     map[string]time.Time
+}
+
+func newHostLimiter(client *http.Client) *hostLimiter {
+	return &hostLimiter{
+		client:   client,
+		checked:  make(map[string]bool),
+		delay:    make(map[string]time.Duration),
+		throttle: make(map[string]time.Duration),
+		slowed:   make(map[string]time.Time),
+		next:     make(map[string]time.Time),
+	}
+}
+
+// wait blocks until a request to host may be sent, or until ctx is done
+func (l *hostLimiter) wait(ctx context.Context, scheme string, host string) {
+	l.mu.Lock()
+	if !l.checked[host] && l.client != nil {
+		// fetch robots.txt without holding the lock, other requests to this host go ahead without a delay meanwhile
+		l.checked[host] = true
+		l.mu.Unlock()
+		delay := l.crawlDelay(scheme, host)
+		l.mu.Lock()
+		l.delay[host] = delay
+	}
+
+	now := time.Now()
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	delay := l.delay[host]
+	if l.throttle[host] > delay {
+		delay = l.throttle[host]
+	}
+	l.next[host] = at.Add(delay)
+	l.mu.Unlock()
+
+	select {
+	case <-time.After(at.Sub(now)):
+	case <-ctx.Done():
+	}
+}
+
+// backoff holds back requests to host for d
+func (l *hostLimiter) backoff(host string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if at := time.Now().Add(d); at.After(l.next[host]) {
+		l.next[host] = at
+	}
+}
+
+// limited slows down requests to host after a 429/503 response. Spacing them out also brings the number in flight
+// down, as requests wait for their turn before being sent. Responses to the requests that were already in flight
+// when it last slowed down don't slow it down further.
+func (l *hostLimiter) limited(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if time.Since(l.slowed[host]) < l.throttle[host] {
+		return
+	}
+	l.slowed[host] = time.Now()
+	throttle := l.throttle[host] * 2
+	if throttle < initialThrottle {
+		throttle = initialThrottle
+	}
+	if throttle > maxThrottle {
+		throttle = maxThrottle
+	}
+	if throttle != l.throttle[host] {
+		log.Println("[throttle] " + host + " is rate limiting, sending a request every " + throttle.String())
+	}
+	l.throttle[host] = throttle
+}
+
+// recovered speeds requests to host back up after a response that wasn't rate limited
+func (l *hostLimiter) recovered(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.throttle[host] == 0 {
+		return
+	}
+	if throttle := l.throttle[host] * 9 / 10; throttle >= initialThrottle/10 {
+		l.throttle[host] = throttle
+	} else {
+		delete(l.throttle, host)
+	}
+}
+
+// crawlDelay returns the Crawl-delay robots.txt specifies for our user agent
+func (l *hostLimiter) crawlDelay(scheme string, host string) time.Duration {
+	resp, err := l.client.Get(scheme + "://" + host + "/robots.txt")
+	if err != nil {
+		return 0
+	}
+	defer resp.Body.Close()
+	robots, err := robotstxt.FromResponse(resp)
+	if err != nil {
+		return 0
+	}
+	if group := robots.FindGroup(UserAgent); group != nil {
+		return group.CrawlDelay
+	}
+	return 0
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at), true
+	}
+	return 0, false
+}
@@ -0,0 +1,103 @@
+package crawler
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+// number of 403 and 429 responses in a row after which a host is taken to be blocking the proxy in use, as a single
+// one may just be a page that is forbidden
+const proxyRefusals = 5
+
+// ProxyRotation moves the requests to a host on to the next proxy of a list once the host blocks them. Hosts start
+// on Config.Proxy, or with no proxy, and move through the list in order.
+type ProxyRotation struct {
+	mu        sync.Mutex
+	proxies   []*url.URL
+	current   map[string]int  // host -> index of the proxy in use plus one, 0 for Config.Proxy
+	refusals  map[string]int  // host -> 403 and 429 responses in a row through the proxy in use
+	exhausted map[string]bool // hosts that blocked requests through every proxy
+}
+
+// NewProxyRotation returns a ProxyRotation through proxies
+func NewProxyRotation(proxies []*url.URL) *ProxyRotation {
+	return &ProxyRotation{
+		proxies:   proxies,
+		current:   make(map[string]int),
+		refusals:  make(map[string]int),
+		exhausted: make(map[string]bool),
+	}
+}
+
+// index returns the position of the proxy requests to host go through, 0 for Config.Proxy
+func (p *ProxyRotation) index(host string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.current[host]
+}
+
+// proxyFunc returns the Proxy function of transports, sending the requests of hosts that blocked fallback through
+// their proxy
+func (p *ProxyRotation) proxyFunc(fallback *url.URL) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		if i := p.index(req.URL.Host); i > 0 {
+			return p.proxies[i-1], nil
+		}
+		return fallback, nil
+	}
+}
+
+// blocked moves host on to the next proxy after a request sent through the proxy at index was blocked, unless
+// another blocked request moved it already. It returns whether the request can be retried through another proxy.
+func (p *ProxyRotation) blocked(host string, index int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.exhausted[host] {
+		return false
+	}
+	if p.current[host] != index {
+		return true
+	}
+	if index == len(p.proxies) {
+		log.Println("[proxy] " + host + " blocks requests through every proxy, giving up")
+		p.exhausted[host] = true
+		return false
+	}
+	p.current[host] = index + 1
+	delete(p.refusals, host)
+	log.Println("[proxy] " + host + " is blocking requests, sending them through proxy " + strconv.Itoa(index+1) + ": " +
+		p.proxies[index].Redacted())
+	return true
+}
+
+// refused counts a 403 or 429 response to a request sent through the proxy at index, moving host on to the next proxy
+// once proxyRefusals of them come in a row. It returns whether requests to host go through another proxy than the one
+// at index.
+func (p *ProxyRotation) refused(host string, index int) bool {
+	p.mu.Lock()
+	if p.exhausted[host] {
+		p.mu.Unlock()
+		return false
+	}
+	if p.current[host] != index {
+		p.mu.Unlock()
+		return true
+	}
+	p.refusals[host]++
+	if p.refusals[host] < proxyRefusals {
+		p.mu.Unlock()
+		return false
+	}
+	p.mu.Unlock()
+	return p.blocked(host, index)
+}
+
+// passed resets the count of 403 and 429 responses of host, after a response that isn't one
+func (p *ProxyRotation) passed(host string) {
+	p.mu.Lock()
+	delete(p.refusals, host)
+	p.mu.Unlock()
+}
@@ -0,0 +1,119 @@
+package crawler
+
+import (
+	"net/http"
+	"sync"
+)
+
+// redirectRecorder follows redirects like colly's default handler does, recording the chain of URLs that led to each final URL
+type redirectRecorder struct {
+	mu     sync.Mutex
+	chains map[string][]string
+}
+
+func newRedirectRecorder() *redirectRecorder {
+	return &redirectRecorder{chains: make(map[string][]string)}
+}
+
+func (rr *redirectRecorder) handle(req *http.Request, via []*http.Request) error {
+	// Honor golangs default of maximum of 10 redirects
+	if len(via) >= 10 {
+		return http.ErrUseLastResponse
+	}
+
+	lastRequest := via[len(via)-1]
+
+	// If domain has changed, remove the Authorization-header if it exists
+	if req.URL.Host != lastRequest.URL.Host {
+		req.Header.Del("Authorization")
+	}
+
+	chain := make([]string, 0, len(via)+1)
+	for _, r := range via {
+		chain = append(chain, r.URL.String())
+	}
+	chain = append(chain, req.URL.String())
+
+	rr.mu.Lock()
+	// the chain is only kept under the url it ends at so far, so a later request for one of its hops doesn't get it
+	previous := lastRequest.URL.String()
+	if stored, ok := rr.chains[previous]; ok && len(stored) == len(via) && stored[0] == chain[0] {
+		delete(rr.chains, previous)
+	}
+	rr.chains[req.URL.String()] = chain
+	rr.mu.Unlock()
+	return nil
+}
+
+// take returns and forgets the chain that ended at final, if there was one
+func (rr *redirectRecorder) take(final string) []string {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	chain := rr.chains[final]
+	for _, hop := range chain {
+		delete(rr.chains, hop)
+	}
+	return chain
+}
+
+// pendingResults holds back the results for links being visited until the URL they resolve to is known
+type pendingResults struct {
+	mu      sync.Mutex
+	results map[string][]Result
+}
+
+func newPendingResults() *pendingResults {
+	return &pendingResults{results: make(map[string][]Result)}
+}
+
+func (p *pendingResults) add(res Result) {
+	p.mu.Lock()
+	p.results[res.URL] = append(p.results[res.URL], res)
+	p.mu.Unlock()
+}
+
+// resolve returns and forgets the results for url, with FinalURL set to final
+func (p *pendingResults) resolve(url string, final string) []Result {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	results := p.results[url]
+	delete(p.results, url)
+	for i := range results {
+		results[i].FinalURL = final
+	}
+	return results
+}
+
+// flush returns and forgets all results that were never resolved, E.g. because the request failed
+func (p *pendingResults) flush() []Result {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var results []Result
+	for url, pending := range p.results {
+		results = append(results, pending...)
+		delete(p.results, url)
+	}
+	return results
+}
+
+// unfollowedRedirects is a RoundTripper reporting where redirect responses point to, for when redirects are disabled.
+// The Location header is removed so the client doesn't even check whether the target is in scope, which would fail
+// the request if it isn't.
+type unfollowedRedirects struct {
+	next  http.RoundTripper
+	found func(from string, to string)
+}
+
+func (t *unfollowedRedirects) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		return resp, err
+	}
+	if location := resp.Header.Get("Location"); location != "" {
+		if target, err := req.URL.Parse(location); err == nil {
+			t.found(req.URL.String(), target.String())
+		}
+		resp.Header.Del("Location")
+	}
+	return resp, err
+}
@@ -0,0 +1,200 @@
+package crawler
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RedisStorage is a colly storage backend that keeps the visited set and cookies in Redis,
+// so several hakrawler instances pointed at the same server share them. It only deduplicates their requests: there is
+// no shared queue, so each instance still crawls from the urls it was given.
+type RedisStorage struct {
+	// Address of the server, E.g. redis://:password@localhost:6379/0
+	Address string
+	// Prefix is prepended to every key
+	Prefix string
+	// Run scopes the visited set, so the instances of a run share it and a later run visits the pages again
+	Run string
+	// TTL is how long keys are kept after the last crawl writing them, 0 to keep them until they are deleted
+	TTL time.Duration
+
+	mu      sync.Mutex
+	conn    net.Conn
+	rd      *bufio.Reader
+	touched time.Time // when the expiry of the visited set was last pushed back
+}
+
+// Init connects to the server, authenticating and selecting the database if the address specifies them
+func (s *RedisStorage) Init() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		return nil
+	}
+	return s.connect()
+}
+
+// connect dials the server. The caller must hold s.mu.
+func (s *RedisStorage) connect() error {
+	u, err := url.Parse(s.Address)
+	if err != nil || u.Scheme != "redis" || u.Host == "" {
+		return errors.New("redis address must be formatted as redis://[:password@]host[:port][/db]")
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host += ":6379"
+	}
+	conn, err := net.DialTimeout("tcp", host, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	s.rd = bufio.NewReader(conn)
+
+	if password, ok := u.User.Password(); ok {
+		if _, err := s.send("AUTH", password); err != nil {
+			s.drop()
+			return err
+		}
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if _, err := s.send("SELECT", db); err != nil {
+			s.drop()
+			return err
+		}
+	}
+	return nil
+}
+
+// drop closes the connection, so the next command dials a new one. The caller must hold s.mu.
+func (s *RedisStorage) drop() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+		s.rd = nil
+	}
+}
+
+// Visited marks the request as visited
+func (s *RedisStorage) Visited(requestID uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := s.visitedKey()
+	if _, err := s.do("SADD", key, strconv.FormatUint(requestID, 10)); err != nil {
+		return err
+	}
+	// the expiry only needs pushing back now and then, rather than after every request
+	if s.TTL > 0 && time.Since(s.touched) > time.Minute {
+		if _, err := s.do("EXPIRE", key, strconv.Itoa(int(s.TTL.Seconds()))); err != nil {
+			return err
+		}
+		s.touched = time.Now()
+	}
+	return nil
+}
+
+func (s *RedisStorage) visitedKey() string {
+	return s.Prefix + ":" + s.Run + ":visited"
+}
+
+// IsVisited returns true if the request was visited by any instance sharing the server
+func (s *RedisStorage) IsVisited(requestID uint64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reply, err := s.do("SISMEMBER", s.visitedKey(), strconv.FormatUint(requestID, 10))
+	return reply == "1", err
+}
+
+// Cookies retrieves the stored cookies for the host of u
+func (s *RedisStorage) Cookies(u *url.URL) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cookies, _ := s.do("GET", s.Prefix+":cookies:"+u.Host)
+	return cookies
+}
+
+// SetCookies stores cookies for the host of u
+func (s *RedisStorage) SetCookies(u *url.URL, cookies string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.TTL > 0 {
+		s.do("SET", s.Prefix+":cookies:"+u.Host, cookies, "EX", strconv.Itoa(int(s.TTL.Seconds())))
+	} else {
+		s.do("SET", s.Prefix+":cookies:"+u.Host, cookies)
+	}
+}
+
+// do sends a command and reads its reply, dialing the server again if the connection was dropped. Bulk and integer
+// replies are returned as strings. The caller must hold s.mu.
+func (s *RedisStorage) do(args ...string) (string, error) {
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return "", err
+		}
+	}
+	reply, err := s.send(args...)
+	var redisErr redisError
+	if err != nil && !errors.As(err, &redisErr) {
+		// the reply of a command that timed out could still arrive and be read as that of the next one, so the
+		// connection is never reused after an error
+		s.drop()
+	}
+	return reply, err
+}
+
+// redisError is an error reply from the server, after which the connection is still in sync
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// send writes a command to the connection and reads its reply. The caller must hold s.mu.
+func (s *RedisStorage) send(args ...string) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	s.conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if _, err := s.conn.Write([]byte(b.String())); err != nil {
+		return "", err
+	}
+
+	line, err := s.rd.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return "", errors.New("empty reply from redis")
+	}
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", redisError(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", err
+		}
+		if n < 0 {
+			return "", nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(s.rd, buf); err != nil {
+			return "", err
+		}
+		return string(buf[:n]), nil
+	}
+	return "", errors.New("unexpected reply from redis: " + line)
+}
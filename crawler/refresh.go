@@ -0,0 +1,28 @@
+package crawler
+
+import (
+	"strings"
+)
+
+// refreshTarget returns the URL of a Refresh header or meta refresh, E.g. "5; url=/next", false if it only reloads the page
+func refreshTarget(value string) (string, bool) {
+	i := strings.IndexAny(value, ";,")
+	if i == -1 {
+		return "", false
+	}
+	target := strings.TrimSpace(value[i+1:])
+	if len(target) >= 4 && strings.EqualFold(target[:3], "url") {
+		if rest := strings.TrimSpace(target[3:]); strings.HasPrefix(rest, "=") {
+			target = strings.TrimSpace(rest[1:])
+		}
+	}
+	// the URL may be quoted, in which case anything after the closing quote is ignored
+	if target != "" && (target[0] == '\'' || target[0] == '"') {
+		quote := target[0]
+		target = target[1:]
+		if end := strings.IndexByte(target, quote); end != -1 {
+			target = target[:end]
+		}
+	}
+	return target, target != ""
+}
@@ -0,0 +1,38 @@
+package crawler
+
+import (
+	"net/url"
+	"regexp"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// match the client-side routes of single page apps in scripts: paths passed to history.pushState/replaceState and
+// path properties of router definitions, E.g. { path: "/admin", component: Admin }
+var (
+	historyRouteRegex = regexp.MustCompile("(?:pushState|replaceState)\\([^)]*?[\"'`](/[^\"'`\\s<>]*)[\"'`]")
+	routerPathRegex   = regexp.MustCompile("\\bpath\\s*:\\s*[\"'`](/[^\"'`\\s<>]*)[\"'`]")
+)
+
+// hashRouteURL returns the absolute URL of a hash route. Like any other link it is relative to the base URL of the
+// page, which is the URL of the page itself unless the page has a <base href>.
+func hashRouteURL(e *colly.HTMLElement, route string) string {
+	base, err := url.Parse(e.Request.AbsoluteURL(""))
+	if err != nil || base.Host == "" {
+		return ""
+	}
+	base.Fragment = ""
+	base.RawFragment = ""
+	return base.String() + route
+}
+
+// scriptRoutes returns the client-side route paths found in a script
+func scriptRoutes(script string) []string {
+	var routes []string
+	for _, regex := range []*regexp.Regexp{historyRouteRegex, routerPathRegex} {
+		for _, match := range regex.FindAllStringSubmatch(script, -1) {
+			routes = append(routes, match[1])
+		}
+	}
+	return routes
+}
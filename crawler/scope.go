@@ -0,0 +1,102 @@
+package crawler
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DefaultNoVisit lists words that mark a URL as destructive to visit, E.g. /account/delete?confirm=1
+var DefaultNoVisit = []string{"logout", "log-out", "signout", "sign-out", "delete", "remove", "deactivate", "destroy", "unsubscribe"}
+
+// isInside reports whether link is on the same host as seed and at or below the seed's path
+func isInside(link string, seed *url.URL) bool {
+	u, err := url.Parse(link)
+	if err != nil || seed == nil {
+		return false
+	}
+	if !strings.EqualFold(u.Host, seed.Host) {
+		return false
+	}
+
+	// compare decoded paths, so differently encoded links to the same path match
+	return hasPathPrefix(u.Path, seed.Path)
+}
+
+// hasPathPrefix reports whether path is prefix itself or below it, treating prefix as a directory
+func hasPathPrefix(path string, prefix string) bool {
+	if prefix == "" || prefix == "/" {
+		return true
+	}
+	if path == prefix || path == strings.TrimSuffix(prefix, "/") {
+		return true
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return strings.HasPrefix(path, prefix)
+}
+
+// isExcludedSub reports whether host is one of the excluded subdomains of hostname, or below one of them.
+// Excludes may be given as bare labels ("dev") or as full hostnames ("dev.example.com").
+func isExcludedSub(host string, hostname string, excludes []string) bool {
+	host = strings.ToLower(host)
+	for _, ex := range excludes {
+		ex = strings.ToLower(strings.TrimSpace(ex))
+		if ex == "" {
+			continue
+		}
+		if !strings.HasSuffix(ex, "."+hostname) {
+			ex += "." + hostname
+		}
+		if host == ex || strings.HasSuffix(host, "."+ex) {
+			return true
+		}
+	}
+	return false
+}
+
+// filtered reports whether link is excluded from the scope by the config, in which case it is neither visited nor printed
+func (config *Config) filtered(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	if len(config.ExcludeSubs) > 0 && isExcludedSub(u.Hostname(), strings.ToLower(config.Hostname), config.ExcludeSubs) {
+		return true
+	}
+	if len(config.Paths) > 0 {
+		for _, prefix := range config.Paths {
+			if hasPathPrefix(u.Path, prefix) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// NoVisitFilter returns a filter matching URLs whose path or query contains any of words, ignoring case. words must
+// not be empty.
+func NoVisitFilter(words []string) *regexp.Regexp {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = regexp.QuoteMeta(word)
+	}
+	return regexp.MustCompile(`(?i)^[^:/?#]+://[^/?#]*[/?#].*(` + strings.Join(quoted, "|") + `)`)
+}
+
+// inScopeHost reports whether host would be crawled: one of the allowed domains or, with -subs, a subdomain of the target
+func (config *Config) inScopeHost(host string) bool {
+	host = strings.ToLower(host)
+	hostname := strings.ToLower(config.Hostname)
+	if config.SubsInScope {
+		return host == hostname || strings.HasSuffix(host, "."+hostname)
+	}
+	for _, domain := range config.AllowedDomains {
+		if host == strings.ToLower(domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package crawler
+
+// SizeRange is a range of response body sizes in bytes, E.g. for filtering out error pages that all have the same size
+type SizeRange struct {
+	Min int
+	Max int // -1 for no upper bound
+}
+
+// contains reports whether size is in the range
+func (r SizeRange) contains(size int) bool {
+	return size >= r.Min && (r.Max == -1 || size <= r.Max)
+}
+
+// sizeFiltered reports whether a page of size bytes should be skipped because of FilterSizes or MatchSizes
+func (config *Config) sizeFiltered(size int) bool {
+	for _, r := range config.FilterSizes {
+		if r.contains(size) {
+			return true
+		}
+	}
+	if len(config.MatchSizes) == 0 {
+		return false
+	}
+	for _, r := range config.MatchSizes {
+		if r.contains(size) {
+			return false
+		}
+	}
+	return true
+}
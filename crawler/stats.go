@@ -0,0 +1,41 @@
+package crawler
+
+import "sync"
+
+// Stats counts the requests a crawl sends and how many of them failed
+type Stats struct {
+	mu       sync.Mutex
+	requests int
+	errors   int
+}
+
+// NewStats returns an empty Stats
+func NewStats() *Stats {
+	return &Stats{}
+}
+
+func (s *Stats) request() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+}
+
+func (s *Stats) failed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors++
+}
+
+// Requests returns the number of requests sent so far
+func (s *Stats) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// Errors returns the number of requests that failed or got an error status
+func (s *Stats) Errors() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.errors
+}
@@ -0,0 +1,60 @@
+package crawler
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// subdomainTracker sends a result the first time each subdomain of hostname is seen
+type subdomainTracker struct {
+	mu       sync.Mutex
+	hostname string
+	seen     map[string]bool
+	results  chan<- Result
+}
+
+func newSubdomainTracker(hostname string, results chan<- Result) *subdomainTracker {
+	return &subdomainTracker{
+		hostname: strings.ToLower(hostname),
+		seen:     make(map[string]bool),
+		results:  results,
+	}
+}
+
+// add records host, if it is a subdomain of the tracked hostname. where is the URL it was seen at.
+func (t *subdomainTracker) add(host string, where string) {
+	host = strings.TrimSuffix(strings.TrimPrefix(strings.ToLower(host), "*."), ".")
+	if host != t.hostname && !strings.HasSuffix(host, "."+t.hostname) {
+		return
+	}
+
+	t.mu.Lock()
+	seen := t.seen[host]
+	t.seen[host] = true
+	t.mu.Unlock()
+
+	if !seen {
+		t.results <- Result{
+			Source: "subdomain",
+			URL:    host,
+			Where:  where,
+		}
+	}
+}
+
+// certNames is a RoundTripper reporting the DNS names of the certificates presented by servers
+type certNames struct {
+	next  http.RoundTripper
+	found func(host string, where string)
+}
+
+func (t *certNames) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err == nil && resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		for _, name := range resp.TLS.PeerCertificates[0].DNSNames {
+			t.found(name, req.URL.String())
+		}
+	}
+	return resp, err
+}
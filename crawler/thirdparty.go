@@ -0,0 +1,45 @@
+package crawler
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ThirdPartyDomains collects the out of scope domains referenced by a crawl, along with how they were referenced
+type ThirdPartyDomains struct {
+	mu      sync.Mutex
+	domains map[string]map[string]bool
+}
+
+// NewThirdPartyDomains returns an empty collection
+func NewThirdPartyDomains() *ThirdPartyDomains {
+	return &ThirdPartyDomains{domains: make(map[string]map[string]bool)}
+}
+
+func (t *ThirdPartyDomains) add(domain string, source string) {
+	domain = strings.ToLower(domain)
+	if domain == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.domains[domain] == nil {
+		t.domains[domain] = make(map[string]bool)
+	}
+	t.domains[domain][source] = true
+}
+
+// Domains returns each domain with the sorted list of sources it was referenced from, E.g. script, iframe, form
+func (t *ThirdPartyDomains) Domains() map[string][]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	domains := make(map[string][]string, len(t.domains))
+	for domain, sources := range t.domains {
+		for source := range sources {
+			domains[domain] = append(domains[domain], source)
+		}
+		sort.Strings(domains[domain])
+	}
+	return domains
+}
@@ -0,0 +1,143 @@
+package crawler
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// limits past which urls are considered to be crawl traps
+const (
+	trapSegmentRepeats = 3    // times a path segment may appear, E.g. /a/b/a/b/a/b from relative links
+	trapParamRepeats   = 3    // times a query parameter may appear, E.g. from forms adding to the current query
+	trapQueryLength    = 1024 // length of query strings that keep growing
+	trapDateVariants   = 50   // urls differing only by dates, E.g. a calendar linking to the next month forever
+)
+
+// dates in paths and query values, E.g. 2024, 2024-05, 20240501 or 2024/05/01
+var (
+	trapDateRegex = regexp.MustCompile(`^(19|20)[0-9]{2}([-/.]?(0[1-9]|1[0-2])([-/.]?(0[1-9]|[12][0-9]|3[01]))?)?$`)
+	trapDayRegex  = regexp.MustCompile(`^[0-9]{1,2}$`)
+)
+
+// query parameters holding part of a date
+var trapDateParams = map[string]bool{"year": true, "month": true, "day": true, "week": true, "date": true, "y": true, "m": true, "d": true}
+
+// errTrapped is the error of visits to urls in crawl traps
+var errTrapped = errors.New("url is in a crawl trap")
+
+// trapDetector recognises urls that expand forever, so a crawl doesn't spend itself on them
+type trapDetector struct {
+	mu       sync.Mutex
+	variants map[string]map[string]bool // url with its dates replaced -> urls seen with it
+	reported map[string]bool
+}
+
+func newTrapDetector() *trapDetector {
+	return &trapDetector{variants: make(map[string]map[string]bool), reported: make(map[string]bool)}
+}
+
+// check returns why link is in a trap, or an empty string if it isn't. report is set for the first url of each trap.
+func (t *trapDetector) check(link string) (reason string, report bool) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", false
+	}
+	key := u.Host
+	reason = trapReason(u)
+	if reason == "" {
+		pattern, dated := datePattern(u)
+		if !dated {
+			return "", false
+		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		seen := t.variants[pattern]
+		if seen == nil {
+			seen = make(map[string]bool)
+			t.variants[pattern] = seen
+		}
+		if seen[link] || len(seen) < trapDateVariants {
+			seen[link] = true
+			return "", false
+		}
+		reason = "urls differing only by dates"
+		key = pattern
+	} else {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+	}
+	key += " " + reason
+	report = !t.reported[key]
+	t.reported[key] = true
+	return reason, report
+}
+
+// trapReason returns why u looks like it was made by a trap on its own, or an empty string
+func trapReason(u *url.URL) string {
+	segments := make(map[string]int)
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment != "" {
+			segments[segment]++
+			if segments[segment] >= trapSegmentRepeats {
+				return "repeating path segment"
+			}
+		}
+	}
+	if len(u.RawQuery) > trapQueryLength {
+		return "ever-growing query string"
+	}
+	for _, values := range u.Query() {
+		if len(values) >= trapParamRepeats {
+			return "repeating query parameter"
+		}
+	}
+	return ""
+}
+
+// datePattern returns u with the dates in its path and query replaced, and whether it has any
+func datePattern(u *url.URL) (string, bool) {
+	dated := false
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		// days and months follow years, E.g. /2024/05/01
+		if trapDateRegex.MatchString(segment) || (i > 0 && segments[i-1] == "{date}" && trapDayRegex.MatchString(segment)) {
+			segments[i] = "{date}"
+			dated = true
+		}
+	}
+
+	query := u.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var params []string
+	for _, name := range names {
+		value := strings.Join(query[name], ",")
+		if trapDateRegex.MatchString(value) || (trapDateParams[strings.ToLower(name)] && trapDayRegex.MatchString(value)) {
+			value = "{date}"
+			dated = true
+		}
+		params = append(params, name+"="+value)
+	}
+	return u.Scheme + "://" + u.Host + strings.Join(segments, "/") + "?" + strings.Join(params, "&"), dated
+}
+
+// trapped reports whether link found at where is in a trap, sending a Result for the first url of each trap
+func (o *output) trapped(where string, link string) bool {
+	if o.traps == nil {
+		return false
+	}
+	reason, report := o.traps.check(link)
+	if report {
+		log.Println("[trap] " + reason + ", not following urls like " + link)
+		o.emit(Result{Source: "trap", URL: link, Where: where, Error: reason})
+	}
+	return reason != ""
+}
@@ -0,0 +1,82 @@
+package crawler
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// truncatedBodies keeps the urls whose response bodies were cut off at MaxSize
+type truncatedBodies struct {
+	mu   sync.Mutex
+	urls map[string]bool
+}
+
+func newTruncatedBodies() *truncatedBodies {
+	return &truncatedBodies{urls: make(map[string]bool)}
+}
+
+func (t *truncatedBodies) add(url string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.urls[url] = true
+}
+
+// take reports whether the body of url was cut off, forgetting it
+func (t *truncatedBodies) take(url string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	truncated := t.urls[url]
+	delete(t.urls, url)
+	return truncated
+}
+
+// sizeLimitTransport stops reading response bodies once they reach a limit, whether or not they have a
+// Content-Length, and closes their connection rather than downloading the rest
+type sizeLimitTransport struct {
+	next      http.RoundTripper
+	limit     int64
+	truncated *truncatedBodies
+}
+
+func (t *sizeLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err == nil {
+		url := req.URL.String()
+		resp.Body = &sizeLimitedBody{ReadCloser: resp.Body, remaining: t.limit, cutOff: func() { t.truncated.add(url) }}
+	}
+	return resp, err
+}
+
+type sizeLimitedBody struct {
+	io.ReadCloser
+	remaining int64
+	cutOff    func() // called once when the body turns out to be longer than the limit
+	done      bool
+}
+
+func (b *sizeLimitedBody) Read(p []byte) (int, error) {
+	if b.done {
+		return 0, io.EOF
+	}
+	if b.remaining <= 0 {
+		// the body ends here if nothing is left to read, otherwise it is cut off
+		var next [1]byte
+		n, err := io.ReadFull(b.ReadCloser, next[:])
+		b.done = true
+		if n > 0 {
+			b.cutOff()
+			return 0, io.EOF
+		}
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// URLSet records the links sent, so each one is only sent once however it was written and wherever it was found
+type URLSet struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// NewURLSet returns an empty URLSet
+func NewURLSet() *URLSet {
+	return &URLSet{seen: make(map[string]bool)}
+}
+
+// add records link, reporting whether it is new
+func (s *URLSet) add(link string) bool {
+	key := canonicalURL(link)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen[key] {
+		return false
+	}
+	s.seen[key] = true
+	return true
+}
+
+// canonicalURL returns link with the differences that don't change what it points to removed: the case of the
+// scheme and host, default ports, empty paths and fragments. Fragments holding a client-side route, E.g. #/admin or
+// #!/admin, are kept, as they point to another page of a single-page app.
+func canonicalURL(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return link
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if (u.Scheme == "http" && u.Port() == "80") || (u.Scheme == "https" && u.Port() == "443") {
+		u.Host = u.Hostname()
+		if strings.Contains(u.Host, ":") {
+			u.Host = "[" + u.Host + "]"
+		}
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	if !strings.HasPrefix(u.Fragment, "/") && !strings.HasPrefix(u.Fragment, "!") {
+		u.Fragment = ""
+		u.RawFragment = ""
+	}
+	return u.String()
+}
@@ -0,0 +1,79 @@
+package crawler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// validator holds the headers a page was returned with that allow requesting it conditionally
+type validator struct {
+	ETag         string `json:",omitempty"`
+	LastModified string `json:",omitempty"`
+}
+
+// Validators keeps the ETag and Last-Modified headers of pages between runs, so pages that haven't changed since
+// return 304 Not Modified and aren't parsed again
+type Validators struct {
+	mu      sync.Mutex
+	entries map[string]validator
+}
+
+// LoadValidators reads the validators saved in filename by a previous run. A missing file gives an empty set.
+func LoadValidators(filename string) (*Validators, error) {
+	v := &Validators{entries: make(map[string]validator)}
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return v, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &v.entries); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Save writes the validators to filename
+func (v *Validators) Save(filename string) error {
+	v.mu.Lock()
+	data, err := json.MarshalIndent(v.entries, "", "  ")
+	v.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+// setConditional adds If-None-Match and If-Modified-Since headers for url, if it was seen before
+func (v *Validators) setConditional(url string, header http.Header) {
+	v.mu.Lock()
+	entry, ok := v.entries[url]
+	v.mu.Unlock()
+	if !ok {
+		return
+	}
+	if entry.ETag != "" {
+		header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		header.Set("If-Modified-Since", entry.LastModified)
+	}
+}
+
+// record stores the validators of a successful response
+func (v *Validators) record(url string, status int, header http.Header) {
+	if status != http.StatusOK {
+		return
+	}
+	entry := validator{ETag: header.Get("ETag"), LastModified: header.Get("Last-Modified")}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if entry == (validator{}) {
+		delete(v.entries, url)
+		return
+	}
+	v.entries[url] = entry
+}
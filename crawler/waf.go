@@ -0,0 +1,154 @@
+package crawler
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// wafSignature tells a WAF apart by its headers and cookies, and its block pages by their body
+type wafSignature struct {
+	name    string
+	headers map[string]*regexp.Regexp // header -> value pattern, nil for any value
+	cookies []string                  // prefixes of the names of the cookies it sets
+	body    *regexp.Regexp            // matches its block and challenge pages
+}
+
+var wafSignatures = []wafSignature{
+	{
+		name:    "cloudflare",
+		headers: map[string]*regexp.Regexp{"Cf-Ray": nil, "Server": regexp.MustCompile(`(?i)^cloudflare`)},
+		cookies: []string{"__cf_bm", "__cfduid", "cf_clearance"},
+		body:    regexp.MustCompile(`(?i)Attention Required! \| Cloudflare|cf-error-details|<title>Just a moment\.\.\.</title>`),
+	},
+	{
+		name:    "akamai",
+		headers: map[string]*regexp.Regexp{"Server": regexp.MustCompile(`(?i)^AkamaiGHost`), "X-Akamai-Transformed": nil},
+		cookies: []string{"ak_bmsc", "bm_sz", "_abck"},
+		body:    regexp.MustCompile(`(?is)<title>Access Denied</title>.*Reference #[0-9a-f.]+`),
+	},
+	{
+		name:    "aws-waf",
+		headers: map[string]*regexp.Regexp{"X-Amzn-Waf-Action": nil},
+		cookies: []string{"aws-waf-token"},
+		body:    regexp.MustCompile(`(?i)<H1>403 ERROR</H1>.*Request blocked|Generated by cloudfront \(CloudFront\)`),
+	},
+	{
+		name:    "imperva",
+		headers: map[string]*regexp.Regexp{"X-Iinfo": nil, "X-Cdn": regexp.MustCompile(`(?i)^Incapsula`)},
+		cookies: []string{"incap_ses_", "visid_incap_", "nlbi_"},
+		body:    regexp.MustCompile(`(?i)Incapsula incident ID|_Incapsula_Resource`),
+	},
+	{
+		name:    "sucuri",
+		headers: map[string]*regexp.Regexp{"X-Sucuri-Id": nil, "Server": regexp.MustCompile(`(?i)^Sucuri`)},
+		body:    regexp.MustCompile(`(?i)Sucuri WebSite Firewall - Access Denied`),
+	},
+	{
+		name:    "f5-asm",
+		cookies: []string{"TS01", "BIGipServer"},
+		body:    regexp.MustCompile(`(?i)The requested URL was rejected\. Please consult with your administrator`),
+	},
+	{
+		name:    "modsecurity",
+		headers: map[string]*regexp.Regexp{"Server": regexp.MustCompile(`(?i)mod_security`)},
+		body:    regexp.MustCompile(`(?i)This error was generated by Mod_Security|ModSecurity Action`),
+	},
+	{
+		name:    "barracuda",
+		cookies: []string{"barra_counter_session", "BNI__BARRACUDA_LB_COOKIE"},
+		body:    regexp.MustCompile(`(?i)Barracuda Networks.*You have been blocked`),
+	},
+	{
+		name: "wordfence",
+		body: regexp.MustCompile(`(?i)Generated by Wordfence|Your access to this site has been limited by the site owner`),
+	},
+}
+
+// statuses WAFs block requests with
+var wafBlockStatuses = map[int]bool{
+	http.StatusForbidden: true, http.StatusNotAcceptable: true, http.StatusTooManyRequests: true,
+	http.StatusServiceUnavailable: true,
+}
+
+// User-Agent headers of current browsers, taken in turn for the hosts that block requests with RotateUserAgent
+var rotatedUserAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:125.0) Gecko/20100101 Firefox/125.0",
+	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.0.0",
+}
+
+// detectWAF returns the WAF a response comes from, empty if none is recognised, and whether it blocked the request
+func detectWAF(status int, headers http.Header, body []byte) (string, bool) {
+	cookies := (&http.Response{Header: headers}).Cookies()
+	for _, signature := range wafSignatures {
+		if signature.body != nil && len(body) > 0 && signature.body.Match(body) {
+			return signature.name, true
+		}
+		seen := false
+		for header, pattern := range signature.headers {
+			if value := headers.Get(header); value != "" && (pattern == nil || pattern.MatchString(value)) {
+				seen = true
+			}
+		}
+		for _, cookie := range cookies {
+			for _, prefix := range signature.cookies {
+				if strings.HasPrefix(cookie.Name, prefix) {
+					seen = true
+				}
+			}
+		}
+		if seen {
+			return signature.name, wafBlockStatuses[status]
+		}
+	}
+	return "", false
+}
+
+// wafTracker keeps the WAF of each host and how many times it blocked requests
+type wafTracker struct {
+	mu     sync.Mutex
+	wafs   map[string]string
+	blocks map[string]int
+}
+
+func newWAFTracker() *wafTracker {
+	return &wafTracker{wafs: make(map[string]string), blocks: make(map[string]int)}
+}
+
+// check looks for a WAF in a response, sending a Result with Source "waf" the first time one is seen on a host.
+// It returns whether the request was blocked.
+func (w *wafTracker) check(o *output, u *url.URL, status int, headers http.Header, body []byte) bool {
+	waf, blocked := detectWAF(status, headers, body)
+	if waf == "" {
+		return false
+	}
+	host := u.Host
+	w.mu.Lock()
+	_, seen := w.wafs[host]
+	w.wafs[host] = waf
+	if blocked {
+		w.blocks[host]++
+	}
+	w.mu.Unlock()
+	if !seen {
+		log.Println("[waf] " + host + " is behind " + waf)
+		o.emit(Result{Source: "waf", URL: u.Scheme + "://" + host, Where: u.String(), Error: waf})
+	}
+	return blocked
+}
+
+// userAgent returns the User-Agent to send to host, empty while it hasn't blocked any request
+func (w *wafTracker) userAgent(host string) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.blocks[host] == 0 {
+		return ""
+	}
+	return rotatedUserAgents[(w.blocks[host]-1)%len(rotatedUserAgents)]
+}
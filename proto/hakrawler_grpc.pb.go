@@ -0,0 +1,173 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: hakrawler.proto
+
+package hakrawlerpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// HakrawlerClient is the client API for Hakrawler service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type HakrawlerClient interface {
+	// SubmitCrawl starts crawling the supplied URLs and returns the job id.
+	SubmitCrawl(ctx context.Context, in *SubmitCrawlRequest, opts ...grpc.CallOption) (*SubmitCrawlResponse, error)
+	// StreamResults streams the results of a job until it has finished.
+	StreamResults(ctx context.Context, in *StreamResultsRequest, opts ...grpc.CallOption) (Hakrawler_StreamResultsClient, error)
+}
+
+type hakrawlerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewHakrawlerClient(cc grpc.ClientConnInterface) HakrawlerClient {
+	return &hakrawlerClient{cc}
+}
+
+func (c *hakrawlerClient) SubmitCrawl(ctx context.Context, in *SubmitCrawlRequest, opts ...grpc.CallOption) (*SubmitCrawlResponse, error) {
+	out := new(SubmitCrawlResponse)
+	err := c.cc.Invoke(ctx, "/hakrawler.Hakrawler/SubmitCrawl", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hakrawlerClient) StreamResults(ctx context.Context, in *StreamResultsRequest, opts ...grpc.CallOption) (Hakrawler_StreamResultsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Hakrawler_ServiceDesc.Streams[0], "/hakrawler.Hakrawler/StreamResults", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &hakrawlerStreamResultsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Hakrawler_StreamResultsClient interface {
+	Recv() (*Result, error)
+	grpc.ClientStream
+}
+
+type hakrawlerStreamResultsClient struct {
+	grpc.ClientStream
+}
+
+func (x *hakrawlerStreamResultsClient) Recv() (*Result, error) {
+	m := new(Result)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// HakrawlerServer is the server API for Hakrawler service.
+// All implementations must embed UnimplementedHakrawlerServer
+// for forward compatibility
+type HakrawlerServer interface {
+	// SubmitCrawl starts crawling the supplied URLs and returns the job id.
+	SubmitCrawl(context.Context, *SubmitCrawlRequest) (*SubmitCrawlResponse, error)
+	// StreamResults streams the results of a job until it has finished.
+	StreamResults(*StreamResultsRequest, Hakrawler_StreamResultsServer) error
+	mustEmbedUnimplementedHakrawlerServer()
+}
+
+// UnimplementedHakrawlerServer must be embedded to have forward compatible implementations.
+type UnimplementedHakrawlerServer struct {
+}
+
+func (UnimplementedHakrawlerServer) SubmitCrawl(context.Context, *SubmitCrawlRequest) (*SubmitCrawlResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitCrawl not implemented")
+}
+func (UnimplementedHakrawlerServer) StreamResults(*StreamResultsRequest, Hakrawler_StreamResultsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamResults not implemented")
+}
+func (UnimplementedHakrawlerServer) mustEmbedUnimplementedHakrawlerServer() {}
+
+// UnsafeHakrawlerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to HakrawlerServer will
+// result in compilation errors.
+type UnsafeHakrawlerServer interface {
+	mustEmbedUnimplementedHakrawlerServer()
+}
+
+func RegisterHakrawlerServer(s grpc.ServiceRegistrar, srv HakrawlerServer) {
+	s.RegisterService(&Hakrawler_ServiceDesc, srv)
+}
+
+func _Hakrawler_SubmitCrawl_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitCrawlRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HakrawlerServer).SubmitCrawl(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hakrawler.Hakrawler/SubmitCrawl",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HakrawlerServer).SubmitCrawl(ctx, req.(*SubmitCrawlRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Hakrawler_StreamResults_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamResultsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(HakrawlerServer).StreamResults(m, &hakrawlerStreamResultsServer{stream})
+}
+
+type Hakrawler_StreamResultsServer interface {
+	Send(*Result) error
+	grpc.ServerStream
+}
+
+type hakrawlerStreamResultsServer struct {
+	grpc.ServerStream
+}
+
+func (x *hakrawlerStreamResultsServer) Send(m *Result) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Hakrawler_ServiceDesc is the grpc.ServiceDesc for Hakrawler service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Hakrawler_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "hakrawler.Hakrawler",
+	HandlerType: (*HakrawlerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SubmitCrawl",
+			Handler:    _Hakrawler_SubmitCrawl_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamResults",
+			Handler:       _Hakrawler_StreamResults_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "hakrawler.proto",
+}